@@ -121,9 +121,48 @@ EOF
             "Action": [
                 "wafv2:GetWebACL",
                 "wafv2:ListResourcesForWebACL",
+                "wafv2:GetSampledRequests",
                 "cloudwatch:GetMetricStatistics",
                 "cloudwatch:ListMetrics",
-                "logs:FilterLogEvents"
+                "cloudwatch:DescribeAlarms",
+                "cloudwatch:DescribeAlarmHistory",
+                "logs:FilterLogEvents",
+                "logs:DescribeLogGroups",
+                "logs:StartQuery",
+                "logs:GetQueryResults",
+                "logs:StopQuery",
+                "rds:DescribeDBClusters",
+                "rds:DescribeDBInstances",
+                "rds:DescribeEvents",
+                "pi:DescribeDimensionKeys",
+                "dynamodb:DescribeTable",
+                "dynamodb:DescribeContinuousBackups",
+                "dynamodb:ListBackups",
+                "tag:GetResources",
+                "ce:GetCostAndUsage",
+                "ce:GetCostForecast",
+                "ce:GetAnomalies",
+                "budgets:ViewBudget",
+                "health:DescribeEvents",
+                "health:DescribeAffectedEntities",
+                "acm:ListCertificates",
+                "acm:DescribeCertificate",
+                "iam:ListServerCertificates",
+                "guardduty:ListDetectors",
+                "guardduty:ListFindings",
+                "guardduty:GetFindings",
+                "securityhub:GetFindings",
+                "securityhub:GetFindingHistory",
+                "cloudtrail:LookupEvents",
+                "servicequotas:GetServiceQuota",
+                "servicequotas:GetAWSDefaultServiceQuota",
+                "route53:GetHealthCheck",
+                "route53:ListHealthChecks",
+                "route53:ListTagsForResources",
+                "ec2:DescribeVolumes",
+                "elasticfilesystem:DescribeFileSystems",
+                "ec2:DescribeNatGateways",
+                "ec2:DescribeVpnConnections"
             ],
             "Resource": "*"
         }
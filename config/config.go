@@ -4,6 +4,9 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"path"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -16,6 +19,8 @@ func LoadEmbeddedConfig() (*Config, error) {
 		return nil, fmt.Errorf("error parsing embedded config JSON: %v", err)
 	}
 
+	applyDefaults(&config)
+
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("embedded config validation failed: %v", err)
 	}
@@ -60,6 +65,51 @@ type MonitoringConfig struct {
 	DailyReportHourUTC int `json:"dailyReportHour"` // Hour of day (0-23)
 }
 
+type WebACLConfig struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"` // REGIONAL (default) or CLOUDFRONT
+}
+
+// QuotaConfig identifies a quota, e.g. lambda/L-B99A9384 for concurrent executions
+type QuotaConfig struct {
+	ServiceCode string `json:"serviceCode"`
+	QuotaCode   string `json:"quotaCode"`
+}
+
+// LogCounter counts the events matching a CloudWatch Logs filter pattern
+type LogCounter struct {
+	Label   string `json:"label"`
+	Pattern string `json:"pattern"`
+}
+
+type LogGroupConfig struct {
+	Name         string       `json:"name"`
+	Counters     []LogCounter `json:"counters"`     // Defaults to the JSON level counters
+	ErrorPattern string       `json:"errorPattern"` // Defaults to the pattern of the ERROR counter
+}
+
+// DefaultLogCounters require structured (JSON) logging with a level field
+var DefaultLogCounters = []LogCounter{
+	{Label: "INFO", Pattern: `{ $.level = "info" }`},
+	{Label: "WARN", Pattern: `{ $.level = "warn" }`},
+	{Label: "ERROR", Pattern: `{ $.level = "error" }`},
+}
+
+// HasDefaultCounters reports whether the log group only uses the JSON level
+// counters, which can be counted with a single Logs Insights query
+func (g LogGroupConfig) HasDefaultCounters() bool {
+	if len(g.Counters) != len(DefaultLogCounters) {
+		return false
+	}
+	for i, counter := range g.Counters {
+		if counter != DefaultLogCounters[i] {
+			return false
+		}
+	}
+	return true
+}
+
 type ServiceConfig struct {
 	EC2 struct {
 		Enabled    bool   `json:"enabled"`
@@ -67,34 +117,75 @@ type ServiceConfig struct {
 	} `json:"ec2"`
 
 	S3 struct {
-		Enabled    bool   `json:"enabled"`
-		BucketName string `json:"bucketName"`
+		Enabled     bool              `json:"enabled"`
+		BucketName  string            `json:"bucketName"`
+		BucketNames []string          `json:"bucketNames"`
+		Tags        map[string]string `json:"tags"` // Discover buckets matching all tags
 	} `json:"s3"`
 
+	EBS struct {
+		Enabled          bool     `json:"enabled"`
+		InstanceIDs      []string `json:"instanceIds"`      // Added to the EC2 and CloudWatch Agent instances
+		ReportUnattached bool     `json:"reportUnattached"` // List unattached volumes in daily reports
+	} `json:"ebs"`
+
+	EFS struct {
+		Enabled       bool     `json:"enabled"`
+		FileSystemIDs []string `json:"fileSystemIds"`
+		Discover      bool     `json:"discover"` // Report every file system of the region
+	} `json:"efs"`
+
+	VPC struct {
+		Enabled           bool     `json:"enabled"`
+		NATGatewayIDs     []string `json:"natGatewayIds"`
+		Discover          bool     `json:"discover"` // Report every available NAT gateway of the region
+		VPNConnectionIDs  []string `json:"vpnConnectionIds"`
+		TransitGatewayIDs []string `json:"transitGatewayIds"`
+	} `json:"vpc"`
+
 	ALB struct {
 		Enabled bool   `json:"enabled"`
 		ALBName string `json:"albName"`
 	} `json:"alb"`
 
 	CloudFront struct {
-		Enabled        bool   `json:"enabled"`
-		DistributionID string `json:"distributionId"`
+		Enabled           bool     `json:"enabled"`
+		DistributionID    string   `json:"distributionId"`
+		DistributionIDs   []string `json:"distributionIds"`
+		AdditionalMetrics bool     `json:"additionalMetrics"` // Must be enabled on the distributions
 	} `json:"cloudfront"`
 
 	CloudWatchAgent struct {
-		Enabled    bool   `json:"enabled"`
-		InstanceID string `json:"instanceId"`
+		Enabled      bool     `json:"enabled"`
+		InstanceID   string   `json:"instanceId"`
+		InstanceIDs  []string `json:"instanceIds"`
+		ProcessNames []string `json:"processNames"` // procstat exe or pattern values
+		// append_dimensions of the agent besides InstanceId (ImageId, InstanceType, AutoScalingGroupName)
+		AppendDimensions []string `json:"appendDimensions"`
 	} `json:"cloudwatchAgent"`
 
 	CloudWatchLogs struct {
-		Enabled       bool     `json:"enabled"`
-		LogGroupNames []string `json:"logGroupNames"`
+		Enabled       bool             `json:"enabled"`
+		LogGroupNames []string         `json:"logGroupNames"` // Counted with the default JSON level counters
+		LogGroups     []LogGroupConfig `json:"logGroups"`
+		TopErrors     int              `json:"topErrors"` // Top error messages per log group in daily reports (default 5)
+		// Discovered at run time and counted with the default JSON level counters
+		LogGroupPatterns []string `json:"logGroupPatterns"` // Globs, e.g. /aws/lambda/orders-*
+		ExcludePatterns  []string `json:"excludePatterns"`  // Globs removed from the discovered groups
+		Forwarding       struct {
+			Enabled          bool     `json:"enabled"`
+			Patterns         []string `json:"patterns"`         // Regular expressions, forwards every line if empty
+			RateLimitMinutes int      `json:"rateLimitMinutes"` // Minimum time between messages per log group (default 5)
+			MaxLines         int      `json:"maxLines"`         // Lines included per message (default 10)
+		} `json:"forwarding"`
 	} `json:"cloudwatchLogs"`
 
 	WAF struct {
-		Enabled    bool   `json:"enabled"`
-		WebACLID   string `json:"webACLId"`
-		WebACLName string `json:"webACLName"`
+		Enabled     bool           `json:"enabled"`
+		WebACLID    string         `json:"webACLId"`
+		WebACLName  string         `json:"webACLName"`
+		WebACLs     []WebACLConfig `json:"webACLs"`
+		SampledTopN int            `json:"sampledTopN"` // Top blocked items in daily reports (default 5, negative disables)
 	} `json:"waf"`
 
 	DynamoDB struct {
@@ -102,11 +193,68 @@ type ServiceConfig struct {
 		TableNames []string `json:"tableNames"`
 	} `json:"dynamodb"`
 
+	Route53 struct {
+		Enabled        bool     `json:"enabled"`
+		HealthCheckIDs []string `json:"healthCheckIds"`
+		Discover       bool     `json:"discover"` // Report every health check of the account
+	} `json:"route53"`
+
 	RDS struct {
 		Enabled              bool   `json:"enabled"`
 		ClusterID            string `json:"clusterId"`
 		DBInstanceIdentifier string `json:"dbInstanceIdentifier"`
 	} `json:"rds"`
+
+	Cost struct {
+		Enabled    bool   `json:"enabled"`
+		TopN       int    `json:"topN"`       // Top services or tag values by month-to-date cost (default 5)
+		GroupByTag string `json:"groupByTag"` // Cost allocation tag key, groups by service if empty
+	} `json:"cost"`
+
+	Health struct {
+		Enabled       bool `json:"enabled"`
+		MaxEntities   int  `json:"maxEntities"`   // Affected resources listed per event (default 5)
+		IncludePublic bool `json:"includePublic"` // Also report public service events that affect no resource of the account
+	} `json:"health"`
+
+	Certificates struct {
+		Enabled      bool     `json:"enabled"`
+		Regions      []string `json:"regions"`      // Defaults to the Lambda region
+		WarningDays  int      `json:"warningDays"`  // Reported in daily reports (default 30)
+		CriticalDays int      `json:"criticalDays"` // Reported in every report (default 7)
+		IncludeIAM   bool     `json:"includeIAM"`   // Also scan IAM server certificates
+	} `json:"certificates"`
+
+	Security struct {
+		Enabled     bool `json:"enabled"`
+		GuardDuty   bool `json:"guardDuty"`
+		SecurityHub bool `json:"securityHub"`
+		TopN        int  `json:"topN"` // Highest-severity findings listed (default 5)
+	} `json:"security"`
+
+	CloudTrail struct {
+		Enabled      bool   `json:"enabled"`
+		LogGroupName string `json:"logGroupName"` // Trail log group, uses LookupEvents in daily reports only if empty
+		MaxEvents    int    `json:"maxEvents"`    // Events listed per report (default 20)
+	} `json:"cloudtrail"`
+
+	Quotas struct {
+		Enabled          bool          `json:"enabled"`
+		Quotas           []QuotaConfig `json:"quotas"`
+		ThresholdPercent float64       `json:"thresholdPercent"` // Flagged above this (default 80)
+	} `json:"quotas"`
+
+	Alarms struct {
+		Enabled    bool              `json:"enabled"`
+		NamePrefix string            `json:"namePrefix"`
+		Tags       map[string]string `json:"tags"` // Only alarms matching all tags
+	} `json:"alarms"`
+
+	Budgets struct {
+		Enabled        bool    `json:"enabled"`
+		WarningPercent float64 `json:"warningPercent"` // Highlighted in the header above this (default 80)
+		AnomalyDays    int     `json:"anomalyDays"`    // Cost anomalies lookback in daily reports (default 7)
+	} `json:"budgets"`
 }
 
 type Config struct {
@@ -114,6 +262,50 @@ type Config struct {
 	Services ServiceConfig `json:"services"`
 }
 
+// applyDefaults fills optional settings left empty in config.json
+func applyDefaults(config *Config) {
+	if config.Services.WAF.SampledTopN == 0 {
+		config.Services.WAF.SampledTopN = 5
+	}
+	if config.Services.CloudWatchLogs.TopErrors == 0 {
+		config.Services.CloudWatchLogs.TopErrors = 5
+	}
+	if config.Services.Health.MaxEntities == 0 {
+		config.Services.Health.MaxEntities = 5
+	}
+	if config.Services.Certificates.WarningDays == 0 {
+		config.Services.Certificates.WarningDays = 30
+	}
+	// Keep the default below a shorter warningDays
+	if config.Services.Certificates.CriticalDays == 0 {
+		config.Services.Certificates.CriticalDays = max(min(7, config.Services.Certificates.WarningDays-1), 0)
+	}
+	if config.Services.Security.TopN == 0 {
+		config.Services.Security.TopN = 5
+	}
+	if config.Services.CloudTrail.MaxEvents == 0 {
+		config.Services.CloudTrail.MaxEvents = 20
+	}
+	if config.Services.Quotas.ThresholdPercent == 0 {
+		config.Services.Quotas.ThresholdPercent = 80
+	}
+	if config.Services.Budgets.WarningPercent == 0 {
+		config.Services.Budgets.WarningPercent = 80
+	}
+	if config.Services.Budgets.AnomalyDays == 0 {
+		config.Services.Budgets.AnomalyDays = 7
+	}
+	if config.Services.Cost.TopN == 0 {
+		config.Services.Cost.TopN = 5
+	}
+	if config.Services.CloudWatchLogs.Forwarding.RateLimitMinutes == 0 {
+		config.Services.CloudWatchLogs.Forwarding.RateLimitMinutes = 5
+	}
+	if config.Services.CloudWatchLogs.Forwarding.MaxLines == 0 {
+		config.Services.CloudWatchLogs.Forwarding.MaxLines = 10
+	}
+}
+
 func validateConfig(config *Config) error {
 	if config.Global.Notifications.UseEmail {
 		if config.Global.Notifications.Email.Host == "" {
@@ -156,27 +348,127 @@ func validateConfig(config *Config) error {
 	if config.Services.EC2.Enabled && config.Services.EC2.InstanceID == "" {
 		return fmt.Errorf("EC2 is enabled but instanceId is empty")
 	}
-	if config.Services.S3.Enabled && config.Services.S3.BucketName == "" {
-		return fmt.Errorf("S3 is enabled but bucketName is empty")
+	if config.Services.S3.Enabled && config.Services.S3.BucketName == "" &&
+		len(config.Services.S3.BucketNames) == 0 && len(config.Services.S3.Tags) == 0 {
+		return fmt.Errorf("S3 is enabled but bucketName, bucketNames and tags are all empty")
 	}
 	if config.Services.ALB.Enabled && config.Services.ALB.ALBName == "" {
 		return fmt.Errorf("ALB is enabled but albName is empty")
 	}
-	if config.Services.CloudFront.Enabled && config.Services.CloudFront.DistributionID == "" {
-		return fmt.Errorf("CloudFront is enabled but distributionId is empty")
+	if config.Services.CloudFront.Enabled && len(config.CloudFrontDistributionIDs()) == 0 {
+		return fmt.Errorf("CloudFront is enabled but distributionId and distributionIds are empty")
+	}
+	if config.Services.CloudWatchAgent.Enabled && len(config.CloudWatchAgentInstanceIDs()) == 0 {
+		return fmt.Errorf("CloudWatch Agent is enabled but instanceId and instanceIds are empty")
+	}
+	if config.Services.CloudWatchLogs.Enabled {
+		logGroups := config.CloudWatchLogGroups()
+		if len(logGroups) == 0 && len(config.Services.CloudWatchLogs.LogGroupPatterns) == 0 {
+			return fmt.Errorf("CloudWatch Logs is enabled but logGroupNames, logGroups and logGroupPatterns arrays are empty")
+		}
+		patterns := append(append([]string{}, config.Services.CloudWatchLogs.LogGroupPatterns...), config.Services.CloudWatchLogs.ExcludePatterns...)
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return fmt.Errorf("invalid CloudWatch Logs log group pattern %q", pattern)
+			}
+		}
+		for _, logGroup := range logGroups {
+			if logGroup.Name == "" {
+				return fmt.Errorf("CloudWatch Logs is enabled but a log group name is empty")
+			}
+			labels := make(map[string]bool)
+			for _, counter := range logGroup.Counters {
+				if counter.Label == "" || counter.Pattern == "" {
+					return fmt.Errorf("log group %s has a counter with an empty label or pattern", logGroup.Name)
+				}
+				if labels[counter.Label] {
+					return fmt.Errorf("log group %s has duplicate counter label %s", logGroup.Name, counter.Label)
+				}
+				labels[counter.Label] = true
+			}
+		}
+		if config.Services.CloudWatchLogs.TopErrors < 0 {
+			return fmt.Errorf("CloudWatch Logs topErrors must not be negative")
+		}
+	}
+	if config.Services.Cost.Enabled && config.Services.Cost.TopN < 0 {
+		return fmt.Errorf("cost topN must not be negative")
+	}
+	if config.Services.Health.Enabled && config.Services.Health.MaxEntities < 0 {
+		return fmt.Errorf("health maxEntities must not be negative")
+	}
+	if certificates := config.Services.Certificates; certificates.Enabled && certificates.CriticalDays > certificates.WarningDays {
+		return fmt.Errorf("certificates criticalDays must not be greater than warningDays")
+	}
+	if security := config.Services.Security; security.Enabled {
+		if !security.GuardDuty && !security.SecurityHub {
+			return fmt.Errorf("security is enabled but guardDuty and securityHub are both false")
+		}
+		if security.TopN < 0 {
+			return fmt.Errorf("security topN must not be negative")
+		}
+	}
+	if config.Services.CloudTrail.Enabled && config.Services.CloudTrail.MaxEvents < 0 {
+		return fmt.Errorf("cloudtrail maxEvents must not be negative")
+	}
+	if config.Services.EBS.Enabled && len(config.EBSInstanceIDs()) == 0 && !config.Services.EBS.ReportUnattached {
+		return fmt.Errorf("EBS is enabled but there are no monitored instances and reportUnattached is false")
 	}
-	if config.Services.CloudWatchAgent.Enabled && config.Services.CloudWatchAgent.InstanceID == "" {
-		return fmt.Errorf("CloudWatch Agent is enabled but instanceId is empty")
+	if config.Services.EFS.Enabled && !config.Services.EFS.Discover && len(config.Services.EFS.FileSystemIDs) == 0 {
+		return fmt.Errorf("EFS is enabled but fileSystemIds is empty and discover is false")
 	}
-	if config.Services.CloudWatchLogs.Enabled && len(config.Services.CloudWatchLogs.LogGroupNames) == 0 {
-		return fmt.Errorf("CloudWatch Logs is enabled but logGroupNames array is empty")
+	if vpc := config.Services.VPC; vpc.Enabled && !vpc.Discover && len(vpc.NATGatewayIDs) == 0 &&
+		len(vpc.VPNConnectionIDs) == 0 && len(vpc.TransitGatewayIDs) == 0 {
+		return fmt.Errorf("VPC is enabled but there are no NAT gateways, VPN connections or transit gateways to monitor")
+	}
+	if config.Services.Route53.Enabled && !config.Services.Route53.Discover && len(config.Services.Route53.HealthCheckIDs) == 0 {
+		return fmt.Errorf("route 53 is enabled but healthCheckIds is empty and discover is false")
+	}
+	if config.Services.Quotas.Enabled {
+		if len(config.Services.Quotas.Quotas) == 0 {
+			return fmt.Errorf("quotas is enabled but quotas array is empty")
+		}
+		for _, quota := range config.Services.Quotas.Quotas {
+			if quota.ServiceCode == "" || quota.QuotaCode == "" {
+				return fmt.Errorf("quotas is enabled but a quota has an empty serviceCode or quotaCode")
+			}
+		}
+	}
+	if config.Services.Budgets.Enabled && (config.Services.Budgets.WarningPercent < 0 || config.Services.Budgets.AnomalyDays < 0) {
+		return fmt.Errorf("budgets warningPercent and anomalyDays must not be negative")
+	}
+	if forwarding := config.Services.CloudWatchLogs.Forwarding; forwarding.Enabled {
+		for _, pattern := range forwarding.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid CloudWatch Logs forwarding pattern %q: %v", pattern, err)
+			}
+		}
+		if forwarding.RateLimitMinutes < 0 || forwarding.MaxLines < 0 {
+			return fmt.Errorf("CloudWatch Logs forwarding rateLimitMinutes and maxLines must not be negative")
+		}
 	}
 	if config.Services.WAF.Enabled {
-		if config.Services.WAF.WebACLID == "" {
-			return fmt.Errorf("WAF is enabled but webACLId is empty")
+		webACLs := config.WAFWebACLs()
+		if len(webACLs) == 0 {
+			return fmt.Errorf("WAF is enabled but no web ACL is configured")
+		}
+		for _, webACL := range webACLs {
+			if webACL.ID == "" {
+				return fmt.Errorf("WAF is enabled but a web ACL id is empty")
+			}
+			if webACL.Name == "" {
+				return fmt.Errorf("WAF is enabled but a web ACL name is empty")
+			}
+			if webACL.Scope != "REGIONAL" && webACL.Scope != "CLOUDFRONT" {
+				return fmt.Errorf("WAF web ACL %s has invalid scope %q - must be REGIONAL or CLOUDFRONT", webACL.Name, webACL.Scope)
+			}
 		}
-		if config.Services.WAF.WebACLName == "" {
-			return fmt.Errorf("WAF is enabled but webACLName is empty")
+	}
+	if config.Services.CloudWatchAgent.Enabled {
+		for _, name := range config.Services.CloudWatchAgent.AppendDimensions {
+			if name != "ImageId" && name != "InstanceType" && name != "AutoScalingGroupName" {
+				return fmt.Errorf("cloudwatchAgent appendDimensions has invalid dimension %q - must be ImageId, InstanceType or AutoScalingGroupName", name)
+			}
 		}
 	}
 	if config.Services.DynamoDB.Enabled && len(config.Services.DynamoDB.TableNames) == 0 {
@@ -191,6 +483,106 @@ func validateConfig(config *Config) error {
 	return nil
 }
 
+// CloudFrontDistributionIDs merges distributionId and distributionIds
+func (c *Config) CloudFrontDistributionIDs() []string {
+	var ids []string
+	if c.Services.CloudFront.DistributionID != "" {
+		ids = append(ids, c.Services.CloudFront.DistributionID)
+	}
+	for _, id := range c.Services.CloudFront.DistributionIDs {
+		if id != "" && id != c.Services.CloudFront.DistributionID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CloudWatchAgentInstanceIDs merges instanceId and instanceIds
+func (c *Config) CloudWatchAgentInstanceIDs() []string {
+	var ids []string
+	if c.Services.CloudWatchAgent.InstanceID != "" {
+		ids = append(ids, c.Services.CloudWatchAgent.InstanceID)
+	}
+	for _, id := range c.Services.CloudWatchAgent.InstanceIDs {
+		if id != "" && id != c.Services.CloudWatchAgent.InstanceID {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// EBSInstanceIDs merges the EBS instanceIds with the instances monitored by
+// the EC2 and CloudWatch Agent collectors
+func (c *Config) EBSInstanceIDs() []string {
+	candidates := append([]string{}, c.Services.EBS.InstanceIDs...)
+	if c.Services.EC2.Enabled {
+		candidates = append(candidates, c.Services.EC2.InstanceID)
+	}
+	if c.Services.CloudWatchAgent.Enabled {
+		candidates = append(candidates, c.CloudWatchAgentInstanceIDs()...)
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range candidates {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CloudWatchLogGroups merges logGroupNames and logGroups, using the default
+// level counters where none are configured
+func (c *Config) CloudWatchLogGroups() []LogGroupConfig {
+	var logGroups []LogGroupConfig
+	for _, name := range c.Services.CloudWatchLogs.LogGroupNames {
+		logGroups = append(logGroups, LogGroupConfig{Name: name, Counters: DefaultLogCounters})
+	}
+	for _, logGroup := range c.Services.CloudWatchLogs.LogGroups {
+		if len(logGroup.Counters) == 0 {
+			logGroup.Counters = DefaultLogCounters
+		}
+		logGroups = append(logGroups, logGroup)
+	}
+	return logGroups
+}
+
+// ErrorFilterPattern returns the filter pattern used to find the top error
+// messages, or an empty string when the log group has none
+func (g LogGroupConfig) ErrorFilterPattern() string {
+	if g.ErrorPattern != "" {
+		return g.ErrorPattern
+	}
+	for _, counter := range g.Counters {
+		if strings.EqualFold(counter.Label, "ERROR") {
+			return counter.Pattern
+		}
+	}
+	return ""
+}
+
+// WAFWebACLs merges the legacy webACLId/webACLName pair with webACLs,
+// defaulting the scope to REGIONAL
+func (c *Config) WAFWebACLs() []WebACLConfig {
+	var webACLs []WebACLConfig
+	if c.Services.WAF.WebACLID != "" || c.Services.WAF.WebACLName != "" {
+		webACLs = append(webACLs, WebACLConfig{
+			ID:    c.Services.WAF.WebACLID,
+			Name:  c.Services.WAF.WebACLName,
+			Scope: "REGIONAL",
+		})
+	}
+	for _, webACL := range c.Services.WAF.WebACLs {
+		if webACL.Scope == "" {
+			webACL.Scope = "REGIONAL"
+		}
+		webACLs = append(webACLs, webACL)
+	}
+	return webACLs
+}
+
 type TimeParams struct {
 	StartTime     time.Time
 	EndTime       time.Time
@@ -0,0 +1,503 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validConfig returns the smallest configuration accepted by validateConfig
+func validConfig() *Config {
+	config := &Config{}
+	config.Global.Notifications.Telegram.BotToken = "token"
+	config.Global.Notifications.Telegram.ChatID = "chat"
+	config.Global.Deployment.LambdaFunctionName = "telegraws"
+	config.Global.Monitoring.DefaultPeriod = 1
+	return config
+}
+
+// checkValidateConfig expects validateConfig to succeed when wantErr is
+// empty, or to fail with an error containing wantErr
+func checkValidateConfig(t *testing.T, config *Config, wantErr string) {
+	t.Helper()
+	err := validateConfig(config)
+	switch {
+	case wantErr == "" && err != nil:
+		t.Errorf("validateConfig() error = %v, want nil", err)
+	case wantErr != "" && err == nil:
+		t.Errorf("validateConfig() error = nil, want %q", wantErr)
+	case wantErr != "" && !strings.Contains(err.Error(), wantErr):
+		t.Errorf("validateConfig() error = %v, want %q", err, wantErr)
+	}
+}
+
+func TestCloudFrontDistributionIDs(t *testing.T) {
+	tests := []struct {
+		name            string
+		distributionID  string
+		distributionIDs []string
+		want            []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:           "legacy id",
+			distributionID: "E1",
+			want:           []string{"E1"},
+		},
+		{
+			name:            "legacy id first without duplicates",
+			distributionID:  "E1",
+			distributionIDs: []string{"E2", "E1", "", "E3"},
+			want:            []string{"E1", "E2", "E3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			config.Services.CloudFront.DistributionID = tt.distributionID
+			config.Services.CloudFront.DistributionIDs = tt.distributionIDs
+
+			if got := config.CloudFrontDistributionIDs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CloudFrontDistributionIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigCloudFront(t *testing.T) {
+	tests := []struct {
+		name            string
+		distributionIDs []string
+		wantErr         string
+	}{
+		{"valid", []string{"E1"}, ""},
+		{"no distribution", nil, "distributionId and distributionIds are empty"},
+		{"only empty ids", []string{""}, "distributionId and distributionIds are empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.CloudFront.Enabled = true
+			config.Services.CloudFront.DistributionIDs = tt.distributionIDs
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+func TestWAFWebACLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		legacy  WebACLConfig
+		webACLs []WebACLConfig
+		want    []WebACLConfig
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "legacy pair defaults to REGIONAL",
+			legacy: WebACLConfig{ID: "id-1", Name: "main"},
+			want:   []WebACLConfig{{ID: "id-1", Name: "main", Scope: "REGIONAL"}},
+		},
+		{
+			name:   "legacy pair first, then webACLs",
+			legacy: WebACLConfig{ID: "id-1", Name: "main"},
+			webACLs: []WebACLConfig{
+				{ID: "id-2", Name: "cdn", Scope: "CLOUDFRONT"},
+				{ID: "id-3", Name: "api"},
+			},
+			want: []WebACLConfig{
+				{ID: "id-1", Name: "main", Scope: "REGIONAL"},
+				{ID: "id-2", Name: "cdn", Scope: "CLOUDFRONT"},
+				{ID: "id-3", Name: "api", Scope: "REGIONAL"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			config.Services.WAF.WebACLID = tt.legacy.ID
+			config.Services.WAF.WebACLName = tt.legacy.Name
+			config.Services.WAF.WebACLs = tt.webACLs
+
+			if got := config.WAFWebACLs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WAFWebACLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigWAF(t *testing.T) {
+	tests := []struct {
+		name    string
+		webACLs []WebACLConfig
+		wantErr string
+	}{
+		{"valid", []WebACLConfig{{ID: "id-1", Name: "main", Scope: "CLOUDFRONT"}}, ""},
+		{"no web ACL", nil, "no web ACL is configured"},
+		{"empty id", []WebACLConfig{{Name: "main"}}, "web ACL id is empty"},
+		{"empty name", []WebACLConfig{{ID: "id-1"}}, "web ACL name is empty"},
+		{"invalid scope", []WebACLConfig{{ID: "id-1", Name: "main", Scope: "GLOBAL"}}, "invalid scope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.WAF.Enabled = true
+			config.Services.WAF.WebACLs = tt.webACLs
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+
+func TestApplyDefaultsSampledTopN(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampledTopN int
+		want        int
+	}{
+		{"unset", 0, 5},
+		{"set", 3, 3},
+		{"negative disables", -1, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.WAF.SampledTopN = tt.sampledTopN
+			applyDefaults(config)
+
+			if got := config.Services.WAF.SampledTopN; got != tt.want {
+				t.Errorf("SampledTopN = %d, want %d", got, tt.want)
+			}
+			if err := validateConfig(config); err != nil {
+				t.Errorf("validateConfig() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestValidateConfigAppendDimensions(t *testing.T) {
+	tests := []struct {
+		name             string
+		enabled          bool
+		appendDimensions []string
+		wantErr          string
+	}{
+		{"none", true, nil, ""},
+		{"all supported", true, []string{"ImageId", "InstanceType", "AutoScalingGroupName"}, ""},
+		{"unsupported", true, []string{"InstanceId"}, "invalid dimension \"InstanceId\""},
+		{"unsupported with the agent disabled", false, []string{"InstanceId"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.CloudWatchAgent.Enabled = tt.enabled
+			config.Services.CloudWatchAgent.InstanceID = "i-0123456789abcdef0"
+			config.Services.CloudWatchAgent.AppendDimensions = tt.appendDimensions
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+
+func TestCloudWatchLogGroups(t *testing.T) {
+	custom := []LogCounter{{Label: "PANIC", Pattern: "panic"}}
+
+	tests := []struct {
+		name          string
+		logGroupNames []string
+		logGroups     []LogGroupConfig
+		want          []LogGroupConfig
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:          "names use the default counters",
+			logGroupNames: []string{"/aws/lambda/a"},
+			want:          []LogGroupConfig{{Name: "/aws/lambda/a", Counters: DefaultLogCounters}},
+		},
+		{
+			name:          "names first, then log groups",
+			logGroupNames: []string{"/aws/lambda/a"},
+			logGroups: []LogGroupConfig{
+				{Name: "/aws/lambda/b", Counters: custom, ErrorPattern: "panic"},
+				{Name: "/aws/lambda/c"},
+			},
+			want: []LogGroupConfig{
+				{Name: "/aws/lambda/a", Counters: DefaultLogCounters},
+				{Name: "/aws/lambda/b", Counters: custom, ErrorPattern: "panic"},
+				{Name: "/aws/lambda/c", Counters: DefaultLogCounters},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			config.Services.CloudWatchLogs.LogGroupNames = tt.logGroupNames
+			config.Services.CloudWatchLogs.LogGroups = tt.logGroups
+
+			if got := config.CloudWatchLogGroups(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CloudWatchLogGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigLogCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		counters []LogCounter
+		wantErr  string
+	}{
+		{"default counters", nil, ""},
+		{"custom counters", []LogCounter{{Label: "PANIC", Pattern: "panic"}, {Label: "OOM", Pattern: "OutOfMemory"}}, ""},
+		{"empty label", []LogCounter{{Pattern: "panic"}}, "empty label or pattern"},
+		{"empty pattern", []LogCounter{{Label: "PANIC"}}, "empty label or pattern"},
+		{"duplicate label", []LogCounter{{Label: "PANIC", Pattern: "panic"}, {Label: "PANIC", Pattern: "fatal"}}, "duplicate counter label PANIC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.CloudWatchLogs.Enabled = true
+			config.Services.CloudWatchLogs.LogGroups = []LogGroupConfig{{Name: "/aws/lambda/a", Counters: tt.counters}}
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+
+func TestHasDefaultCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		counters []LogCounter
+		want     bool
+	}{
+		{"default counters", DefaultLogCounters, true},
+		{"copy of the default counters", append([]LogCounter{}, DefaultLogCounters...), true},
+		{"no counters", nil, false},
+		{"subset", DefaultLogCounters[:2], false},
+		{"reordered", []LogCounter{DefaultLogCounters[2], DefaultLogCounters[1], DefaultLogCounters[0]}, false},
+		{"changed pattern", []LogCounter{DefaultLogCounters[0], DefaultLogCounters[1], {Label: "ERROR", Pattern: "ERROR"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logGroup := LogGroupConfig{Name: "/aws/lambda/a", Counters: tt.counters}
+			if got := logGroup.HasDefaultCounters(); got != tt.want {
+				t.Errorf("HasDefaultCounters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorFilterPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		logGroup LogGroupConfig
+		want     string
+	}{
+		{
+			name:     "default counters",
+			logGroup: LogGroupConfig{Counters: DefaultLogCounters},
+			want:     `{ $.level = "error" }`,
+		},
+		{
+			name:     "errorPattern wins",
+			logGroup: LogGroupConfig{Counters: DefaultLogCounters, ErrorPattern: "?ERROR ?FATAL"},
+			want:     "?ERROR ?FATAL",
+		},
+		{
+			name:     "ERROR label is case insensitive",
+			logGroup: LogGroupConfig{Counters: []LogCounter{{Label: "Error", Pattern: "ERROR"}}},
+			want:     "ERROR",
+		},
+		{
+			name:     "no ERROR counter",
+			logGroup: LogGroupConfig{Counters: []LogCounter{{Label: "PANIC", Pattern: "panic"}}},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.logGroup.ErrorFilterPattern(); got != tt.want {
+				t.Errorf("ErrorFilterPattern() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigLogGroupPatterns(t *testing.T) {
+	tests := []struct {
+		name            string
+		patterns        []string
+		excludePatterns []string
+		wantErr         string
+	}{
+		{"patterns only", []string{"/aws/lambda/*"}, nil, ""},
+		{"with exclude patterns", []string{"/aws/lambda/*"}, []string{"/aws/lambda/*-test"}, ""},
+		{"malformed pattern", []string{"/aws/lambda/[orders"}, nil, "invalid CloudWatch Logs log group pattern"},
+		{"malformed exclude pattern", []string{"/aws/lambda/*"}, []string{"/aws/lambda/\\"}, "invalid CloudWatch Logs log group pattern"},
+		{"empty pattern", []string{""}, nil, "invalid CloudWatch Logs log group pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.CloudWatchLogs.Enabled = true
+			config.Services.CloudWatchLogs.LogGroupPatterns = tt.patterns
+			config.Services.CloudWatchLogs.ExcludePatterns = tt.excludePatterns
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+
+func TestApplyDefaultsCertificates(t *testing.T) {
+	tests := []struct {
+		name             string
+		warningDays      int
+		criticalDays     int
+		wantWarningDays  int
+		wantCriticalDays int
+	}{
+		{"unset", 0, 0, 30, 7},
+		{"short warningDays", 5, 0, 5, 4},
+		{"one day warningDays", 1, 0, 1, 0},
+		{"both set", 60, 14, 60, 14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.Certificates.Enabled = true
+			config.Services.Certificates.WarningDays = tt.warningDays
+			config.Services.Certificates.CriticalDays = tt.criticalDays
+			applyDefaults(config)
+
+			if got := config.Services.Certificates.WarningDays; got != tt.wantWarningDays {
+				t.Errorf("WarningDays = %d, want %d", got, tt.wantWarningDays)
+			}
+			if got := config.Services.Certificates.CriticalDays; got != tt.wantCriticalDays {
+				t.Errorf("CriticalDays = %d, want %d", got, tt.wantCriticalDays)
+			}
+			if err := validateConfig(config); err != nil {
+				t.Errorf("validateConfig() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestValidateConfigCertificates(t *testing.T) {
+	tests := []struct {
+		name         string
+		warningDays  int
+		criticalDays int
+		wantErr      string
+	}{
+		{"critical below warning", 30, 7, ""},
+		{"critical equals warning", 14, 14, ""},
+		{"critical above warning", 5, 7, "criticalDays must not be greater than warningDays"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.Certificates.Enabled = true
+			config.Services.Certificates.WarningDays = tt.warningDays
+			config.Services.Certificates.CriticalDays = tt.criticalDays
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
+
+func TestEBSInstanceIDs(t *testing.T) {
+	tests := []struct {
+		name            string
+		ebsInstanceIDs  []string
+		ec2Enabled      bool
+		ec2InstanceID   string
+		cwAgentEnabled  bool
+		cwAgentInstance string
+		cwAgentIDs      []string
+		want            []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:           "EBS instances only",
+			ebsInstanceIDs: []string{"i-1", "", "i-2"},
+			want:           []string{"i-1", "i-2"},
+		},
+		{
+			name:            "disabled collectors are ignored",
+			ebsInstanceIDs:  []string{"i-1"},
+			ec2InstanceID:   "i-2",
+			cwAgentInstance: "i-3",
+			want:            []string{"i-1"},
+		},
+		{
+			name:            "merged without duplicates",
+			ebsInstanceIDs:  []string{"i-1", "i-2"},
+			ec2Enabled:      true,
+			ec2InstanceID:   "i-2",
+			cwAgentEnabled:  true,
+			cwAgentInstance: "i-3",
+			cwAgentIDs:      []string{"i-1", "i-4"},
+			want:            []string{"i-1", "i-2", "i-3", "i-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			config.Services.EBS.InstanceIDs = tt.ebsInstanceIDs
+			config.Services.EC2.Enabled = tt.ec2Enabled
+			config.Services.EC2.InstanceID = tt.ec2InstanceID
+			config.Services.CloudWatchAgent.Enabled = tt.cwAgentEnabled
+			config.Services.CloudWatchAgent.InstanceID = tt.cwAgentInstance
+			config.Services.CloudWatchAgent.InstanceIDs = tt.cwAgentIDs
+
+			if got := config.EBSInstanceIDs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EBSInstanceIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfigEBS(t *testing.T) {
+	tests := []struct {
+		name             string
+		instanceIDs      []string
+		reportUnattached bool
+		wantErr          string
+	}{
+		{"instances", []string{"i-1"}, false, ""},
+		{"unattached only", nil, true, ""},
+		{"nothing to report", nil, false, "no monitored instances and reportUnattached is false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			config.Services.EBS.Enabled = true
+			config.Services.EBS.InstanceIDs = tt.instanceIDs
+			config.Services.EBS.ReportUnattached = tt.reportUnattached
+
+			checkValidateConfig(t, config, tt.wantErr)
+		})
+	}
+}
@@ -2,19 +2,41 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"time"
 
 	"telegraws/config"
 	"telegraws/services"
 	"telegraws/utils"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/budgets"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+	"github.com/aws/aws-sdk-go-v2/service/health"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/pi"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/wafv2"
 	"go.uber.org/zap"
 )
@@ -36,8 +58,41 @@ func logic(ctx context.Context) error {
 	}
 
 	cwClient := cloudwatch.NewFromConfig(awsCfg)
+	// Global services (CloudFront, Route 53, CloudFront WAF) publish their metrics in us-east-1
+	cwGlobalClient := cloudwatch.NewFromConfig(awsCfg, func(o *cloudwatch.Options) {
+		o.Region = "us-east-1"
+	})
 	logsClient := cloudwatchlogs.NewFromConfig(awsCfg)
 	wafClient := wafv2.NewFromConfig(awsCfg)
+	// CLOUDFRONT scoped web ACLs can only be managed from us-east-1
+	wafGlobalClient := wafv2.NewFromConfig(awsCfg, func(o *wafv2.Options) {
+		o.Region = "us-east-1"
+	})
+	ec2Client := ec2.NewFromConfig(awsCfg)
+	efsClient := efs.NewFromConfig(awsCfg)
+	rdsClient := rds.NewFromConfig(awsCfg)
+	piClient := pi.NewFromConfig(awsCfg)
+	ddbClient := dynamodb.NewFromConfig(awsCfg)
+	taggingClient := resourcegroupstaggingapi.NewFromConfig(awsCfg)
+	// The AWS Health API is only served from us-east-1
+	healthClient := health.NewFromConfig(awsCfg, func(o *health.Options) {
+		o.Region = "us-east-1"
+	})
+	ctClient := cloudtrail.NewFromConfig(awsCfg)
+	// IAM and console sign-in events are recorded in us-east-1
+	ctGlobalClient := cloudtrail.NewFromConfig(awsCfg, func(o *cloudtrail.Options) {
+		o.Region = "us-east-1"
+	})
+	r53Client := route53.NewFromConfig(awsCfg)
+	sqClient := servicequotas.NewFromConfig(awsCfg)
+	gdClient := guardduty.NewFromConfig(awsCfg)
+	shClient := securityhub.NewFromConfig(awsCfg)
+	stsClient := sts.NewFromConfig(awsCfg)
+	budgetsClient := budgets.NewFromConfig(awsCfg)
+	// Cost Explorer is only served from us-east-1
+	ceClient := costexplorer.NewFromConfig(awsCfg, func(o *costexplorer.Options) {
+		o.Region = "us-east-1"
+	})
 
 	allMetrics := make(map[string]any)
 
@@ -56,11 +111,143 @@ func logic(ctx context.Context) error {
 	}
 
 	if appConfig.Services.S3.Enabled && timeParams.IsDailyReport {
-		s3Metrics, err := services.S3Metrics(ctx, cwClient, appConfig.Services.S3.BucketName, timeParamsMap)
+		bucketNames := appConfig.Services.S3.BucketNames
+		if appConfig.Services.S3.BucketName != "" {
+			bucketNames = append([]string{appConfig.Services.S3.BucketName}, bucketNames...)
+		}
+		if len(appConfig.Services.S3.Tags) > 0 {
+			discovered, err := services.DiscoverS3Buckets(ctx, taggingClient, appConfig.Services.S3.Tags)
+			if err != nil {
+				utils.Logger.Error("Failed to discover S3 buckets", zap.Error(err))
+			} else {
+				bucketNames = append(bucketNames, discovered...)
+			}
+		}
+
+		var s3Buckets []*utils.S3Bucket
+		seen := make(map[string]bool)
+		for _, bucketName := range bucketNames {
+			if seen[bucketName] {
+				continue
+			}
+			seen[bucketName] = true
+
+			s3Metrics, err := services.S3Metrics(ctx, cwClient, bucketName, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get S3 metrics",
+					zap.Error(err),
+					zap.String("bucketName", bucketName),
+				)
+				continue
+			}
+			s3Buckets = append(s3Buckets, s3Metrics)
+		}
+		if len(s3Buckets) > 0 {
+			allMetrics["s3"] = s3Buckets
+		}
+	}
+
+	if appConfig.Services.EBS.Enabled {
+		ebsReport := &utils.EBSReport{}
+
+		if instanceIDs := appConfig.EBSInstanceIDs(); len(instanceIDs) > 0 {
+			volumes, err := services.GetAttachedVolumes(ctx, ec2Client, instanceIDs)
+			if err != nil {
+				utils.Logger.Error("Failed to get attached EBS volumes", zap.Error(err))
+			}
+			for _, volume := range volumes {
+				if err := services.EBSMetrics(ctx, cwClient, volume, timeParamsMap); err != nil {
+					utils.Logger.Error("Failed to get EBS metrics",
+						zap.Error(err),
+						zap.String("volumeID", volume.VolumeID),
+					)
+					continue
+				}
+				ebsReport.Volumes = append(ebsReport.Volumes, volume)
+			}
+		}
+
+		if appConfig.Services.EBS.ReportUnattached && timeParams.IsDailyReport {
+			unattached, err := services.GetUnattachedVolumes(ctx, ec2Client)
+			if err != nil {
+				utils.Logger.Error("Failed to get unattached EBS volumes", zap.Error(err))
+			}
+			ebsReport.Unattached = unattached
+		}
+
+		allMetrics["ebs"] = ebsReport
+	}
+
+	if appConfig.Services.EFS.Enabled {
+		fileSystems, err := services.GetEFSFileSystems(ctx, efsClient, appConfig.Services.EFS.FileSystemIDs, appConfig.Services.EFS.Discover)
 		if err != nil {
-			utils.Logger.Error("Failed to get S3 metrics", zap.Error(err))
-		} else {
-			allMetrics["s3"] = s3Metrics
+			utils.Logger.Error("Failed to get EFS file systems", zap.Error(err))
+		}
+
+		var efsFileSystems []*utils.EFSFileSystem
+		for _, fileSystem := range fileSystems {
+			if err := services.EFSMetrics(ctx, cwClient, fileSystem, timeParamsMap); err != nil {
+				utils.Logger.Error("Failed to get EFS metrics",
+					zap.Error(err),
+					zap.String("fileSystemID", fileSystem.FileSystemID),
+				)
+				continue
+			}
+			efsFileSystems = append(efsFileSystems, fileSystem)
+		}
+		if len(efsFileSystems) > 0 {
+			allMetrics["efs"] = efsFileSystems
+		}
+	}
+
+	if appConfig.Services.VPC.Enabled {
+		vpcConfig := appConfig.Services.VPC
+
+		if vpcConfig.Discover || len(vpcConfig.NATGatewayIDs) > 0 {
+			natGateways, err := services.GetNATGateways(ctx, ec2Client, vpcConfig.NATGatewayIDs, vpcConfig.Discover)
+			if err != nil {
+				utils.Logger.Error("Failed to get NAT gateways", zap.Error(err))
+			}
+
+			var natReports []*utils.NATGateway
+			for _, natGateway := range natGateways {
+				if err := services.NATGatewayMetrics(ctx, cwClient, natGateway, timeParamsMap); err != nil {
+					utils.Logger.Error("Failed to get NAT gateway metrics",
+						zap.Error(err),
+						zap.String("natGatewayID", natGateway.NATGatewayID),
+					)
+					continue
+				}
+				natReports = append(natReports, natGateway)
+			}
+			if len(natReports) > 0 {
+				allMetrics["natGateways"] = natReports
+			}
+		}
+
+		if len(vpcConfig.VPNConnectionIDs) > 0 {
+			tunnels, err := services.VPNTunnels(ctx, ec2Client, vpcConfig.VPNConnectionIDs)
+			if err != nil {
+				utils.Logger.Error("Failed to get VPN tunnels", zap.Error(err))
+			} else if len(tunnels) > 0 {
+				allMetrics["vpnTunnels"] = tunnels
+			}
+		}
+
+		var transitGateways []*utils.TransitGateway
+		for _, transitGatewayID := range vpcConfig.TransitGatewayIDs {
+			transitGateway, err := services.TransitGatewayMetrics(ctx, cwClient, transitGatewayID, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get transit gateway metrics",
+					zap.Error(err),
+					zap.String("transitGatewayID", transitGatewayID),
+				)
+				continue
+			}
+			transitGateways = append(transitGateways, transitGateway)
+		}
+		if len(transitGateways) > 0 {
+			allMetrics["transitGateways"] = transitGateways
 		}
 	}
 
@@ -74,55 +261,141 @@ func logic(ctx context.Context) error {
 	}
 
 	if appConfig.Services.CloudFront.Enabled {
-		cloudFrontMetrics, err := services.CloudFrontMetrics(ctx, cwClient, appConfig.Services.CloudFront.DistributionID, timeParamsMap)
-		if err != nil {
-			utils.Logger.Error("Failed to get CloudFront metrics", zap.Error(err))
-		} else {
-			allMetrics["cloudfront"] = cloudFrontMetrics
+		distributionMetrics := make(map[string]any)
+		for _, distributionID := range appConfig.CloudFrontDistributionIDs() {
+			cloudFrontMetrics, err := services.CloudFrontMetrics(ctx, cwGlobalClient, distributionID, appConfig.Services.CloudFront.AdditionalMetrics, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get CloudFront metrics",
+					zap.Error(err),
+					zap.String("distributionId", distributionID),
+				)
+				continue
+			}
+			distributionMetrics[distributionID] = cloudFrontMetrics
+		}
+		if len(distributionMetrics) > 0 {
+			allMetrics["cloudfront"] = distributionMetrics
 		}
 	}
 
 	if appConfig.Services.CloudWatchAgent.Enabled {
-		cwAgentMetrics, err := services.CWAgentMetrics(ctx, cwClient, appConfig.Services.CloudWatchAgent.InstanceID, timeParamsMap)
-		if err != nil {
-			utils.Logger.Error("Failed to get CloudWatch Agent metrics", zap.Error(err))
-		} else {
-			allMetrics["cloudwatchAgent"] = cwAgentMetrics
+		var agentInstances []*utils.CWAgentInstance
+		for _, instanceID := range appConfig.CloudWatchAgentInstanceIDs() {
+			cwAgentMetrics, err := services.CWAgentMetrics(ctx, cwClient, instanceID, appConfig.Services.CloudWatchAgent.ProcessNames, appConfig.Services.CloudWatchAgent.AppendDimensions, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get CloudWatch Agent metrics",
+					zap.Error(err),
+					zap.String("instanceId", instanceID),
+				)
+				continue
+			}
+			agentInstances = append(agentInstances, cwAgentMetrics)
+		}
+		if len(agentInstances) > 0 {
+			allMetrics["cloudwatchAgent"] = agentInstances
 		}
 	}
 
 	if appConfig.Services.CloudWatchLogs.Enabled {
 		logMetrics := make(map[string]any)
-		for _, logGroupName := range appConfig.Services.CloudWatchLogs.LogGroupNames {
-			logCounts, err := services.CWLogs(ctx, logsClient, logGroupName, timeParamsMap)
+		logGroups := appConfig.CloudWatchLogGroups()
+
+		if len(appConfig.Services.CloudWatchLogs.LogGroupPatterns) > 0 {
+			discovered, err := services.DiscoverLogGroups(ctx, logsClient, appConfig.Services.CloudWatchLogs.LogGroupPatterns, appConfig.Services.CloudWatchLogs.ExcludePatterns)
+			if err != nil {
+				utils.Logger.Error("Failed to discover CloudWatch log groups",
+					zap.Error(err),
+					zap.Int("discovered", len(discovered)),
+				)
+			}
+
+			// Explicitly configured groups keep their own counters
+			configured := make(map[string]bool)
+			for _, logGroup := range logGroups {
+				configured[logGroup.Name] = true
+			}
+			for _, name := range discovered {
+				if !configured[name] {
+					logGroups = append(logGroups, config.LogGroupConfig{Name: name, Counters: config.DefaultLogCounters})
+				}
+			}
+		}
+		allMetrics["cloudwatchLogGroups"] = logGroups
+
+		insightsCounts, err := services.CWLogsInsights(ctx, logsClient, logGroups, timeParamsMap)
+		if err != nil {
+			utils.Logger.Error("Failed to count logs with Logs Insights, falling back to FilterLogEvents", zap.Error(err))
+		}
+
+		for _, logGroup := range logGroups {
+			if logCounts, ok := insightsCounts[logGroup.Name]; ok {
+				logMetrics[logGroup.Name] = logCounts
+				continue
+			}
+
+			logCounts, err := services.CWLogs(ctx, logsClient, logGroup.Name, logGroup.Counters, timeParamsMap)
 			if err != nil {
 				utils.Logger.Error("Failed to get CloudWatch Logs metrics",
 					zap.Error(err),
-					zap.String("logGroup", logGroupName),
+					zap.String("logGroup", logGroup.Name),
 				)
 				continue
 			}
-			logMetrics[logGroupName] = logCounts
+			logMetrics[logGroup.Name] = logCounts
 		}
 		if len(logMetrics) > 0 {
 			allMetrics["cloudwatchLogs"] = logMetrics
 		}
+
+		if timeParams.IsDailyReport {
+			logErrors := make(map[string][]utils.LogErrorGroup)
+			for _, logGroup := range logGroups {
+				errorPattern := logGroup.ErrorFilterPattern()
+				if errorPattern == "" {
+					continue
+				}
+
+				topErrors, err := services.CWLogsTopErrors(ctx, logsClient, logGroup.Name, errorPattern, appConfig.Services.CloudWatchLogs.TopErrors, timeParamsMap)
+				if err != nil {
+					utils.Logger.Error("Failed to get top error messages",
+						zap.Error(err),
+						zap.String("logGroup", logGroup.Name),
+					)
+					continue
+				}
+				logErrors[logGroup.Name] = topErrors
+			}
+			allMetrics["cloudwatchLogsErrors"] = logErrors
+		}
 	}
 
 	if appConfig.Services.WAF.Enabled {
-		wafMetrics, err := services.WAFMetrics(ctx, wafClient, cwClient, appConfig.Services.WAF.WebACLID, appConfig.Services.WAF.WebACLName, timeParamsMap)
-		if err != nil {
-			utils.Logger.Error("Failed to get WAF metrics", zap.Error(err))
-		} else {
-			allMetrics["waf"] = wafMetrics
-		}
+		var webACLMetrics []*utils.WAFWebACL
+		for _, webACL := range appConfig.WAFWebACLs() {
+			webACLWAFClient, webACLCWClient := wafClient, cwClient
+			if webACL.Scope == "CLOUDFRONT" {
+				webACLWAFClient, webACLCWClient = wafGlobalClient, cwGlobalClient
+			}
 
+			wafMetrics, err := services.WAFMetrics(ctx, webACLWAFClient, webACLCWClient, webACL.ID, webACL.Name, webACL.Scope, awsCfg.Region, timeParamsMap, timeParams.IsDailyReport, appConfig.Services.WAF.SampledTopN)
+			if err != nil {
+				utils.Logger.Error("Failed to get WAF metrics",
+					zap.Error(err),
+					zap.String("webACLName", webACL.Name),
+				)
+				continue
+			}
+			webACLMetrics = append(webACLMetrics, wafMetrics)
+		}
+		if len(webACLMetrics) > 0 {
+			allMetrics["waf"] = webACLMetrics
+		}
 	}
 
 	if appConfig.Services.DynamoDB.Enabled {
 		dynamoMetrics := make(map[string]any)
 		for _, tableName := range appConfig.Services.DynamoDB.TableNames {
-			dynamodbMetrics, err := services.DynamoDBMetrics(ctx, cwClient, timeParamsMap, tableName)
+			dynamodbMetrics, err := services.DynamoDBMetrics(ctx, cwClient, ddbClient, timeParamsMap, tableName)
 			if err != nil {
 				utils.Logger.Error("Failed to get DynamoDB metrics",
 					zap.Error(err),
@@ -137,8 +410,28 @@ func logic(ctx context.Context) error {
 		}
 	}
 
+	if appConfig.Services.Route53.Enabled {
+		healthChecks, err := services.GetRoute53HealthChecks(ctx, r53Client, appConfig.Services.Route53.HealthCheckIDs, appConfig.Services.Route53.Discover)
+		if err != nil {
+			utils.Logger.Error("Failed to get Route 53 health checks", zap.Error(err))
+		}
+
+		for _, healthCheck := range healthChecks {
+			// Route 53 is a global service, its metrics live in us-east-1
+			if err := services.Route53Metrics(ctx, cwGlobalClient, healthCheck, timeParamsMap); err != nil {
+				utils.Logger.Error("Failed to get Route 53 health check metrics",
+					zap.Error(err),
+					zap.String("healthCheckID", healthCheck.ID),
+				)
+			}
+		}
+		if len(healthChecks) > 0 {
+			allMetrics["route53"] = healthChecks
+		}
+	}
+
 	if appConfig.Services.RDS.Enabled {
-		rdsMetrics, err := services.RDSMetrics(ctx, cwClient, appConfig.Services.RDS.ClusterID, appConfig.Services.RDS.DBInstanceIdentifier, timeParamsMap)
+		rdsMetrics, err := services.RDSMetrics(ctx, cwClient, rdsClient, piClient, appConfig.Services.RDS.ClusterID, appConfig.Services.RDS.DBInstanceIdentifier, timeParamsMap, timeParams.IsDailyReport)
 		if err != nil {
 			utils.Logger.Error("Failed to get RDS metrics", zap.Error(err))
 		} else {
@@ -146,8 +439,172 @@ func logic(ctx context.Context) error {
 		}
 	}
 
+	// Cost Explorer data is daily and each request is billed
+	if appConfig.Services.Cost.Enabled && timeParams.IsDailyReport {
+		costReport, err := services.CostMetrics(ctx, ceClient, appConfig.Services.Cost.GroupByTag, appConfig.Services.Cost.TopN, timeParams.EndTime)
+		if err != nil {
+			utils.Logger.Error("Failed to get cost report", zap.Error(err))
+		} else {
+			allMetrics["cost"] = costReport
+		}
+	}
+
+	if appConfig.Services.Health.Enabled {
+		healthEvents, err := services.HealthEvents(ctx, healthClient, awsCfg.Region, appConfig.Services.Health.MaxEntities, appConfig.Services.Health.IncludePublic)
+		if err != nil {
+			utils.Logger.Error("Failed to get AWS Health events", zap.Error(err))
+		} else {
+			allMetrics["health"] = healthEvents
+		}
+	}
+
+	if appConfig.Services.Certificates.Enabled {
+		c := appConfig.Services.Certificates
+		regions := c.Regions
+		if len(regions) == 0 {
+			regions = []string{awsCfg.Region}
+		}
+
+		var certificates []utils.Certificate
+		for _, region := range regions {
+			acmClient := acm.NewFromConfig(awsCfg, func(o *acm.Options) {
+				o.Region = region
+			})
+			regionCertificates, err := services.ACMCertificates(ctx, acmClient, region, c.WarningDays, c.CriticalDays, timeParams.EndTime)
+			if err != nil {
+				utils.Logger.Error("Failed to get ACM certificates",
+					zap.Error(err),
+					zap.String("region", region),
+				)
+				continue
+			}
+			certificates = append(certificates, regionCertificates...)
+		}
+
+		if c.IncludeIAM {
+			iamCertificates, err := services.IAMServerCertificates(ctx, iam.NewFromConfig(awsCfg), c.WarningDays, c.CriticalDays, timeParams.EndTime)
+			if err != nil {
+				utils.Logger.Error("Failed to get IAM server certificates", zap.Error(err))
+			}
+			certificates = append(certificates, iamCertificates...)
+		}
+
+		sort.Slice(certificates, func(i, j int) bool {
+			return certificates[i].DaysLeft < certificates[j].DaysLeft
+		})
+		allMetrics["certificates"] = certificates
+	}
+
+	if appConfig.Services.Security.Enabled {
+		var findings []utils.SecurityFinding
+		if appConfig.Services.Security.GuardDuty {
+			gdFindings, err := services.GuardDutyFindings(ctx, gdClient, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get GuardDuty findings", zap.Error(err))
+			}
+			findings = append(findings, gdFindings...)
+		}
+		if appConfig.Services.Security.SecurityHub {
+			shFindings, err := services.SecurityHubFailedControls(ctx, shClient, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get Security Hub findings", zap.Error(err))
+			}
+			findings = append(findings, shFindings...)
+		}
+		allMetrics["security"] = findings
+	}
+
+	// LookupEvents takes one throttled call per event name, over 10s, so
+	// without a trail log group the digest is only built for daily reports
+	cloudTrailConfig := appConfig.Services.CloudTrail
+	if cloudTrailConfig.Enabled && (cloudTrailConfig.LogGroupName != "" || timeParams.IsDailyReport) {
+		var trailEvents []utils.CloudTrailEvent
+		var err error
+		if cloudTrailConfig.LogGroupName != "" {
+			trailEvents, err = services.CloudTrailLogGroupEvents(ctx, logsClient, cloudTrailConfig.LogGroupName, timeParamsMap)
+		} else {
+			trailEvents, err = services.CloudTrailEvents(ctx, ctClient, ctGlobalClient, timeParamsMap)
+		}
+		if err != nil {
+			utils.Logger.Error("Failed to get CloudTrail events", zap.Error(err))
+		} else {
+			allMetrics["cloudtrail"] = trailEvents
+		}
+	}
+
+	if appConfig.Services.Quotas.Enabled {
+		var quotaUsages []*utils.QuotaUsage
+		for _, quota := range appConfig.Services.Quotas.Quotas {
+			usage, err := services.QuotaUsage(ctx, sqClient, cwClient, quota.ServiceCode, quota.QuotaCode, timeParamsMap)
+			if err != nil {
+				utils.Logger.Error("Failed to get quota usage",
+					zap.Error(err),
+					zap.String("serviceCode", quota.ServiceCode),
+					zap.String("quotaCode", quota.QuotaCode),
+				)
+				continue
+			}
+			quotaUsages = append(quotaUsages, usage)
+		}
+		if len(quotaUsages) > 0 {
+			allMetrics["quotas"] = quotaUsages
+		}
+	}
+
+	if appConfig.Services.Alarms.Enabled {
+		alarmsReport, err := services.AlarmsMetrics(ctx, cwClient, taggingClient, appConfig.Services.Alarms.NamePrefix, appConfig.Services.Alarms.Tags, timeParamsMap)
+		if err != nil {
+			utils.Logger.Error("Failed to get CloudWatch alarms", zap.Error(err))
+		} else {
+			allMetrics["alarms"] = alarmsReport
+		}
+	}
+
+	if appConfig.Services.Budgets.Enabled {
+		budgetsReport := &utils.BudgetsReport{}
+
+		// Budgets are listed per account
+		identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			utils.Logger.Error("Failed to get account ID for budgets", zap.Error(err))
+		} else {
+			budgetsReport.Budgets, err = services.BudgetsMetrics(ctx, budgetsClient, aws.ToString(identity.Account))
+			if err != nil {
+				utils.Logger.Error("Failed to get budgets", zap.Error(err))
+			}
+		}
+
+		// GetAnomalies is billed per request like the rest of Cost Explorer
+		if timeParams.IsDailyReport {
+			budgetsReport.Anomalies, err = services.CostAnomalies(ctx, ceClient, appConfig.Services.Budgets.AnomalyDays, timeParams.EndTime)
+			if err != nil {
+				utils.Logger.Error("Failed to get cost anomalies", zap.Error(err))
+			}
+		}
+
+		allMetrics["budgets"] = budgetsReport
+	}
+
 	message := utils.BuildMessage(appConfig, timeParams, allMetrics, appConfig.Global.Notifications.UseEmail)
 
+	if err := sendNotification(ctx, appConfig, message); err != nil {
+		return err
+	}
+
+	// Report the lines held back by the log forwarding rate limit, which are
+	// otherwise lost when no later event arrives for the log group
+	if forwarding := appConfig.Services.CloudWatchLogs.Forwarding; forwarding.Enabled {
+		rateLimit := time.Duration(forwarding.RateLimitMinutes) * time.Minute
+		if err := sendLogAlerts(ctx, appConfig, logForwarder.Flush(rateLimit)); err != nil {
+			utils.Logger.Error("Failed to send log alert summaries", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// Helper function to send a message through the configured channel
+func sendNotification(ctx context.Context, appConfig *config.Config, message string) error {
 	if appConfig.Global.Notifications.UseEmail {
 		e := appConfig.Global.Notifications.Email
 		if err := utils.SendToEmail(
@@ -174,12 +631,72 @@ func logic(ctx context.Context) error {
 	return nil
 }
 
+// Kept across invocations of the same warm container for rate limiting
+var logForwarder = services.NewLogForwarder()
+
+// forwardLogs sends the lines of a CloudWatch Logs subscription event
+// matching the forwarding patterns
+func forwardLogs(ctx context.Context, event events.CloudwatchLogsEvent) error {
+	appConfig, err := config.LoadEmbeddedConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load app config: %v", err)
+	}
+
+	forwarding := appConfig.Services.CloudWatchLogs.Forwarding
+	if !forwarding.Enabled {
+		utils.Logger.Warn("Received a CloudWatch Logs event but forwarding is disabled")
+		return nil
+	}
+
+	rateLimit := time.Duration(forwarding.RateLimitMinutes) * time.Minute
+	alert, err := logForwarder.Process(event, forwarding.Patterns, rateLimit, forwarding.MaxLines)
+	if err != nil {
+		return err
+	}
+
+	// Returning the error makes Lambda retry the event
+	if alert != nil {
+		if err := sendLogAlerts(ctx, appConfig, []*utils.LogAlert{alert}); err != nil {
+			return err
+		}
+	}
+
+	// Also report other log groups whose rate limit window is over. Failed
+	// summaries keep their lines held back for the next flush.
+	if err := sendLogAlerts(ctx, appConfig, logForwarder.Flush(rateLimit)); err != nil {
+		utils.Logger.Error("Failed to send log alert summaries", zap.Error(err))
+	}
+
+	return nil
+}
+
+// Helper function to send log alerts, starting the rate limit window of the
+// ones that are delivered
+func sendLogAlerts(ctx context.Context, appConfig *config.Config, alerts []*utils.LogAlert) error {
+	var errs []error
+	for _, alert := range alerts {
+		message := utils.BuildLogAlertMessage(alert, appConfig.Global.Notifications.UseEmail)
+		if err := sendNotification(ctx, appConfig, message); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send log alert for %s: %w", alert.LogGroup, err))
+			continue
+		}
+		logForwarder.MarkSent(alert, time.Now())
+	}
+	return errors.Join(errs...)
+}
+
 func main() {
 	ctx := context.Background()
 	defer utils.Logger.Sync()
 
 	if os.Getenv("AWS_LAMBDA_RUNTIME_API") != "" {
-		lambda.Start(func(ctx context.Context) error {
+		// Scheduled invocations run the report, subscription filters send
+		// their log events under the awslogs key
+		lambda.Start(func(ctx context.Context, payload json.RawMessage) error {
+			var logsEvent events.CloudwatchLogsEvent
+			if err := json.Unmarshal(payload, &logsEvent); err == nil && logsEvent.AWSLogs.Data != "" {
+				return forwardLogs(ctx, logsEvent)
+			}
 			return logic(ctx)
 		})
 	} else {
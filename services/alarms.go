@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingTypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+)
+
+// Alarms with the most transitions listed in the report
+const alarmsTopTransitions = 5
+
+// AlarmsMetrics lists the metric and composite alarms matching the name
+// prefix and all the tags that are in ALARM or INSUFFICIENT_DATA, and counts
+// their state transitions during the report window from the alarm history.
+func AlarmsMetrics(ctx context.Context, cwClient *cloudwatch.Client, taggingClient *resourcegroupstaggingapi.Client, namePrefix string, tags map[string]string, timeParams map[string]time.Time) (*utils.AlarmsReport, error) {
+	var taggedArns map[string]bool
+	if len(tags) > 0 {
+		var err error
+		taggedArns, err = getTaggedAlarmArns(ctx, taggingClient, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmTypes: []types.AlarmType{types.AlarmTypeMetricAlarm, types.AlarmTypeCompositeAlarm},
+	}
+	if namePrefix != "" {
+		input.AlarmNamePrefix = aws.String(namePrefix)
+	}
+
+	report := &utils.AlarmsReport{}
+	monitored := map[string]bool{}
+	addAlarm := func(arn, name *string, state types.StateValue, reason *string, since time.Time) {
+		if taggedArns != nil && !taggedArns[aws.ToString(arn)] {
+			return
+		}
+		report.Total++
+		monitored[aws.ToString(name)] = true
+
+		if state == types.StateValueAlarm || state == types.StateValueInsufficientData {
+			report.Alarms = append(report.Alarms, utils.AlarmState{
+				Name:   aws.ToString(name),
+				State:  string(state),
+				Reason: aws.ToString(reason),
+				Since:  since,
+			})
+		}
+	}
+
+	paginator := cloudwatch.NewDescribeAlarmsPaginator(cwClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe alarms: %w", err)
+		}
+
+		for _, alarm := range output.MetricAlarms {
+			addAlarm(alarm.AlarmArn, alarm.AlarmName, alarm.StateValue, alarm.StateReason,
+				alarmSince(alarm.StateTransitionedTimestamp, alarm.StateUpdatedTimestamp))
+		}
+		for _, alarm := range output.CompositeAlarms {
+			addAlarm(alarm.AlarmArn, alarm.AlarmName, alarm.StateValue, alarm.StateReason,
+				alarmSince(alarm.StateTransitionedTimestamp, alarm.StateUpdatedTimestamp))
+		}
+	}
+
+	// ALARM before INSUFFICIENT_DATA, most recent first
+	sort.Slice(report.Alarms, func(i, j int) bool {
+		if report.Alarms[i].State != report.Alarms[j].State {
+			return report.Alarms[i].State == string(types.StateValueAlarm)
+		}
+		return report.Alarms[i].Since.After(report.Alarms[j].Since)
+	})
+
+	historyInput := &cloudwatch.DescribeAlarmHistoryInput{
+		AlarmTypes:      []types.AlarmType{types.AlarmTypeMetricAlarm, types.AlarmTypeCompositeAlarm},
+		HistoryItemType: types.HistoryItemTypeStateUpdate,
+		StartDate:       aws.Time(timeParams["startTime"]),
+		EndDate:         aws.Time(timeParams["endTime"]),
+	}
+
+	transitions := map[string]int64{}
+	historyPaginator := cloudwatch.NewDescribeAlarmHistoryPaginator(cwClient, historyInput)
+	for historyPaginator.HasMorePages() {
+		output, err := historyPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe alarm history: %w", err)
+		}
+
+		for _, item := range output.AlarmHistoryItems {
+			name := aws.ToString(item.AlarmName)
+			if !monitored[name] {
+				continue
+			}
+			report.Transitions++
+			transitions[name]++
+		}
+	}
+	report.TopTransitions = topCounts(transitions, alarmsTopTransitions)
+
+	return report, nil
+}
+
+// Helper function to get when an alarm entered its state. StateUpdatedTimestamp
+// also moves when the reason or data is refreshed, so it is only used when the
+// transition time is missing.
+func alarmSince(transitioned, updated *time.Time) time.Time {
+	if transitioned != nil {
+		return *transitioned
+	}
+	return aws.ToTime(updated)
+}
+
+// Helper function to get the ARNs of the alarms matching all the given tags
+func getTaggedAlarmArns(ctx context.Context, taggingClient *resourcegroupstaggingapi.Client, tags map[string]string) (map[string]bool, error) {
+	var tagFilters []taggingTypes.TagFilter
+	for key, value := range tags {
+		tagFilters = append(tagFilters, taggingTypes.TagFilter{
+			Key:    aws.String(key),
+			Values: []string{value},
+		})
+	}
+
+	input := &resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: []string{"cloudwatch:alarm"},
+		TagFilters:          tagFilters,
+	}
+
+	arns := map[string]bool{}
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(taggingClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tagged alarms: %w", err)
+		}
+
+		for _, resource := range output.ResourceTagMappingList {
+			arns[aws.ToString(resource.ResourceARN)] = true
+		}
+	}
+
+	return arns, nil
+}
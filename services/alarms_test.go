@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlarmSince(t *testing.T) {
+	transitioned := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	updated := time.Date(2024, 5, 2, 7, 55, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		transitioned *time.Time
+		updated      *time.Time
+		want         time.Time
+	}{
+		{"state transition", &transitioned, &updated, transitioned},
+		{"no transition time", nil, &updated, updated},
+		{"no timestamps", nil, nil, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := alarmSince(tt.transitioned, tt.updated); !got.Equal(tt.want) {
+				t.Errorf("alarmSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
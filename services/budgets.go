@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/budgets"
+	budgetsTypes "github.com/aws/aws-sdk-go-v2/service/budgets/types"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	ceTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+)
+
+// BudgetsMetrics reports the actual and forecasted spend of every cost and
+// usage budget of the account as a percent of its limit. Utilization and
+// coverage budgets are skipped since they have no spend limit.
+func BudgetsMetrics(ctx context.Context, budgetsClient *budgets.Client, accountID string) ([]utils.BudgetStatus, error) {
+	input := &budgets.DescribeBudgetsInput{
+		AccountId: aws.String(accountID),
+	}
+
+	var statuses []utils.BudgetStatus
+	paginator := budgets.NewDescribeBudgetsPaginator(budgetsClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe budgets: %w", err)
+		}
+
+		for _, budget := range output.Budgets {
+			if status, ok := newBudgetStatus(budget); ok {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ActualPercent > statuses[j].ActualPercent
+	})
+
+	return statuses, nil
+}
+
+// CostAnomalies returns the open Cost Anomaly Detection anomalies of the last
+// days, skipping the ones dismissed with NO or PLANNED_ACTIVITY feedback.
+// Cost data lags by a day, so an anomaly is open while its end date is not
+// before yesterday.
+func CostAnomalies(ctx context.Context, ceClient *costexplorer.Client, days int, now time.Time) ([]utils.CostAnomaly, error) {
+	input := &costexplorer.GetAnomaliesInput{
+		DateInterval: &ceTypes.AnomalyDateInterval{
+			StartDate: aws.String(now.AddDate(0, 0, -days).Format(time.DateOnly)),
+			EndDate:   aws.String(now.Format(time.DateOnly)),
+		},
+	}
+
+	yesterday := now.AddDate(0, 0, -1).Format(time.DateOnly)
+
+	var anomalies []utils.CostAnomaly
+	paginator := costexplorer.NewGetAnomaliesPaginator(ceClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost anomalies: %w", err)
+		}
+
+		for _, anomaly := range output.Anomalies {
+			if !isOpenAnomaly(anomaly, yesterday) {
+				continue
+			}
+
+			item := utils.CostAnomaly{
+				StartDate: aws.ToString(anomaly.AnomalyStartDate),
+				EndDate:   aws.ToString(anomaly.AnomalyEndDate),
+			}
+			if anomaly.Impact != nil {
+				item.Impact = anomaly.Impact.TotalImpact
+				item.ImpactPercent = aws.ToFloat64(anomaly.Impact.TotalImpactPercentage)
+			}
+			// The first root cause has the highest contribution
+			if len(anomaly.RootCauses) > 0 {
+				rootCause := anomaly.RootCauses[0]
+				item.Service = aws.ToString(rootCause.Service)
+				item.Region = aws.ToString(rootCause.Region)
+				item.UsageType = aws.ToString(rootCause.UsageType)
+			}
+			if item.Service == "" {
+				item.Service = aws.ToString(anomaly.DimensionValue)
+			}
+
+			anomalies = append(anomalies, item)
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Impact > anomalies[j].Impact
+	})
+
+	return anomalies, nil
+}
+
+// Helper function to convert a budget into its spend status. Only cost and
+// usage budgets with a single limit are reported.
+func newBudgetStatus(budget budgetsTypes.Budget) (utils.BudgetStatus, bool) {
+	if budget.BudgetType != budgetsTypes.BudgetTypeCost && budget.BudgetType != budgetsTypes.BudgetTypeUsage {
+		return utils.BudgetStatus{}, false
+	}
+	// Budgets with planned limits per period have no BudgetLimit
+	if budget.BudgetLimit == nil {
+		return utils.BudgetStatus{}, false
+	}
+
+	status := utils.BudgetStatus{
+		Name:     aws.ToString(budget.BudgetName),
+		TimeUnit: string(budget.TimeUnit),
+		Limit:    spendAmount(budget.BudgetLimit),
+		Unit:     aws.ToString(budget.BudgetLimit.Unit),
+	}
+	if budget.CalculatedSpend != nil {
+		status.Actual = spendAmount(budget.CalculatedSpend.ActualSpend)
+		status.Forecast = spendAmount(budget.CalculatedSpend.ForecastedSpend)
+	}
+	if status.Limit > 0 {
+		status.ActualPercent = status.Actual / status.Limit * 100
+		status.ForecastPercent = status.Forecast / status.Limit * 100
+	}
+
+	return status, true
+}
+
+// Helper function to check that an anomaly is neither dismissed nor over.
+// yesterday is a YYYY-MM-DD date.
+func isOpenAnomaly(anomaly ceTypes.Anomaly, yesterday string) bool {
+	if anomaly.Feedback == ceTypes.AnomalyFeedbackTypeNo || anomaly.Feedback == ceTypes.AnomalyFeedbackTypePlannedActivity {
+		return false
+	}
+	// Dates may include a time, compare the date part only
+	endDate := aws.ToString(anomaly.AnomalyEndDate)
+	return endDate == "" || endDate[:min(len(endDate), 10)] >= yesterday
+}
+
+// Helper function to parse a budget amount, which the API returns as a string
+func spendAmount(spend *budgetsTypes.Spend) float64 {
+	if spend == nil {
+		return 0
+	}
+	amount, _ := strconv.ParseFloat(aws.ToString(spend.Amount), 64)
+	return amount
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	budgetsTypes "github.com/aws/aws-sdk-go-v2/service/budgets/types"
+	ceTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+)
+
+func TestNewBudgetStatus(t *testing.T) {
+	spend := func(amount string) *budgetsTypes.Spend {
+		return &budgetsTypes.Spend{Amount: aws.String(amount), Unit: aws.String("USD")}
+	}
+	budget := func(budgetType budgetsTypes.BudgetType, limit *budgetsTypes.Spend) budgetsTypes.Budget {
+		return budgetsTypes.Budget{
+			BudgetName:  aws.String("monthly"),
+			BudgetType:  budgetType,
+			TimeUnit:    budgetsTypes.TimeUnitMonthly,
+			BudgetLimit: limit,
+			CalculatedSpend: &budgetsTypes.CalculatedSpend{
+				ActualSpend:     spend("80"),
+				ForecastedSpend: spend("120"),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		budget  budgetsTypes.Budget
+		want    bool
+		wantPct [2]float64 // actual and forecast percent
+	}{
+		{"cost budget", budget(budgetsTypes.BudgetTypeCost, spend("100")), true, [2]float64{80, 120}},
+		{"usage budget", budget(budgetsTypes.BudgetTypeUsage, spend("200")), true, [2]float64{40, 60}},
+		{"zero limit", budget(budgetsTypes.BudgetTypeCost, spend("0")), true, [2]float64{0, 0}},
+		{"planned limits", budget(budgetsTypes.BudgetTypeCost, nil), false, [2]float64{}},
+		{"RI utilization", budget(budgetsTypes.BudgetTypeRIUtilization, spend("90")), false, [2]float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, ok := newBudgetStatus(tt.budget)
+			if ok != tt.want {
+				t.Fatalf("newBudgetStatus() ok = %v, want %v", ok, tt.want)
+			}
+			if got := [2]float64{status.ActualPercent, status.ForecastPercent}; got != tt.wantPct {
+				t.Errorf("newBudgetStatus() percents = %v, want %v", got, tt.wantPct)
+			}
+		})
+	}
+}
+
+func TestIsOpenAnomaly(t *testing.T) {
+	const yesterday = "2024-05-01"
+
+	tests := []struct {
+		name     string
+		endDate  *string
+		feedback ceTypes.AnomalyFeedbackType
+		want     bool
+	}{
+		{"ongoing", nil, "", true},
+		{"ended yesterday", aws.String("2024-05-01"), "", true},
+		{"ended yesterday with a time", aws.String("2024-05-01T00:00:00Z"), "", true},
+		{"ended before yesterday", aws.String("2024-04-30"), "", false},
+		{"confirmed", nil, ceTypes.AnomalyFeedbackTypeYes, true},
+		{"dismissed", nil, ceTypes.AnomalyFeedbackTypeNo, false},
+		{"planned activity", nil, ceTypes.AnomalyFeedbackTypePlannedActivity, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomaly := ceTypes.Anomaly{AnomalyEndDate: tt.endDate, Feedback: tt.feedback}
+			if got := isOpenAnomaly(anomaly, yesterday); got != tt.want {
+				t.Errorf("isOpenAnomaly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
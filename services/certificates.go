@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmTypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"go.uber.org/zap"
+)
+
+// ACMCertificates returns the certificates of a region expiring within
+// warningDays, with their renewal eligibility and the resources using them.
+// Expired certificates are only reported while still in use.
+func ACMCertificates(ctx context.Context, acmClient *acm.Client, region string, warningDays, criticalDays int, now time.Time) ([]utils.Certificate, error) {
+	input := &acm.ListCertificatesInput{
+		CertificateStatuses: []acmTypes.CertificateStatus{
+			acmTypes.CertificateStatusIssued,
+			acmTypes.CertificateStatusExpired,
+		},
+		// Only RSA_2048 certificates are listed by default
+		Includes: &acmTypes.Filters{
+			KeyTypes: acmTypes.KeyAlgorithm("").Values(),
+		},
+	}
+
+	var certificates []utils.Certificate
+	paginator := acm.NewListCertificatesPaginator(acmClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ACM certificates in %s: %w", region, err)
+		}
+
+		for _, summary := range output.CertificateSummaryList {
+			if summary.NotAfter == nil {
+				continue
+			}
+			daysLeft := certificateDaysLeft(*summary.NotAfter, now)
+			if daysLeft > warningDays {
+				continue
+			}
+			if summary.Status == acmTypes.CertificateStatusExpired && !aws.ToBool(summary.InUse) {
+				continue
+			}
+
+			certificate := utils.Certificate{
+				Source:             "ACM",
+				Region:             region,
+				DomainName:         aws.ToString(summary.DomainName),
+				Type:               string(summary.Type),
+				NotAfter:           aws.ToTime(summary.NotAfter),
+				DaysLeft:           daysLeft,
+				Severity:           certificateSeverity(daysLeft, criticalDays),
+				RenewalEligibility: string(summary.RenewalEligibility),
+			}
+
+			// InUseBy is only returned by DescribeCertificate
+			if aws.ToBool(summary.InUse) {
+				describeOutput, err := acmClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{
+					CertificateArn: summary.CertificateArn,
+				})
+				if err != nil {
+					utils.Logger.Error("Failed to describe ACM certificate",
+						zap.Error(err),
+						zap.String("domainName", certificate.DomainName),
+						zap.String("region", region),
+					)
+				} else {
+					certificate.InUseBy = describeOutput.Certificate.InUseBy
+				}
+			}
+
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	return certificates, nil
+}
+
+// IAMServerCertificates returns the IAM server certificates expiring within
+// warningDays. IAM certificates are never renewed automatically.
+func IAMServerCertificates(ctx context.Context, iamClient *iam.Client, warningDays, criticalDays int, now time.Time) ([]utils.Certificate, error) {
+	var certificates []utils.Certificate
+	paginator := iam.NewListServerCertificatesPaginator(iamClient, &iam.ListServerCertificatesInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list IAM server certificates: %w", err)
+		}
+
+		for _, metadata := range output.ServerCertificateMetadataList {
+			if metadata.Expiration == nil {
+				continue
+			}
+			daysLeft := certificateDaysLeft(*metadata.Expiration, now)
+			if daysLeft > warningDays {
+				continue
+			}
+
+			certificates = append(certificates, utils.Certificate{
+				Source:             "IAM",
+				Region:             "global",
+				DomainName:         aws.ToString(metadata.ServerCertificateName),
+				Type:               "IMPORTED",
+				NotAfter:           aws.ToTime(metadata.Expiration),
+				DaysLeft:           daysLeft,
+				Severity:           certificateSeverity(daysLeft, criticalDays),
+				RenewalEligibility: string(acmTypes.RenewalEligibilityIneligible),
+			})
+		}
+	}
+
+	return certificates, nil
+}
+
+// Helper function to get the whole days left before expiry, negative once
+// the certificate has expired
+func certificateDaysLeft(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+func certificateSeverity(daysLeft, criticalDays int) string {
+	if daysLeft <= criticalDays {
+		return "CRITICAL"
+	}
+	return "WARNING"
+}
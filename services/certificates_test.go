@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCertificateDaysLeft(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     int
+	}{
+		{"whole days", now.Add(30 * 24 * time.Hour), 30},
+		{"partial day rounds down", now.Add(30*24*time.Hour - time.Minute), 29},
+		{"later today", now.Add(6 * time.Hour), 0},
+		{"expired an hour ago", now.Add(-time.Hour), -1},
+		{"expired two days ago", now.Add(-48 * time.Hour), -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certificateDaysLeft(tt.notAfter, now); got != tt.want {
+				t.Errorf("certificateDaysLeft() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificateSeverity(t *testing.T) {
+	tests := []struct {
+		daysLeft     int
+		criticalDays int
+		want         string
+	}{
+		{30, 7, "WARNING"},
+		{8, 7, "WARNING"},
+		{7, 7, "CRITICAL"},
+		{0, 7, "CRITICAL"},
+		{-1, 7, "CRITICAL"},
+		{1, 0, "WARNING"},
+		{0, 0, "CRITICAL"},
+	}
+
+	for _, tt := range tests {
+		if got := certificateSeverity(tt.daysLeft, tt.criticalDays); got != tt.want {
+			t.Errorf("certificateSeverity(%d, %d) = %s, want %s", tt.daysLeft, tt.criticalDays, got, tt.want)
+		}
+	}
+}
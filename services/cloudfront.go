@@ -10,24 +10,41 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 )
 
-func CloudFrontMetrics(ctx context.Context, cwClient *cloudwatch.Client, distributionID string, timeParams map[string]time.Time) (map[string]float64, error) {
+// CloudFront metrics only exist in us-east-1 with the Region=Global
+// dimension, so cwClient must be configured for us-east-1.
+func CloudFrontMetrics(ctx context.Context, cwClient *cloudwatch.Client, distributionID string, additionalMetrics bool, timeParams map[string]time.Time) (map[string]float64, error) {
 	metrics := map[string]float64{}
 	period := aws.Int32(3600)
 	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
 		period = aws.Int32(86400)
 	}
 
-	cloudFrontMetrics := []struct {
+	type cloudFrontMetric struct {
 		Name      string
 		Statistic string
 		Unit      string
-	}{
-		{"Requests", "Sum", "Count"},
-		{"BytesDownloaded", "Sum", "Bytes"},
+	}
+
+	cloudFrontMetrics := []cloudFrontMetric{
+		{"Requests", "Sum", "None"},
+		{"BytesDownloaded", "Sum", "None"},
 		{"4xxErrorRate", "Average", "Percent"},
 		{"5xxErrorRate", "Average", "Percent"},
-		{"CacheHitRate", "Average", "Percent"},
-		{"OriginLatency", "Average", "Milliseconds"},
+	}
+
+	// Only published when additional metrics are enabled on the distribution
+	if additionalMetrics {
+		cloudFrontMetrics = append(cloudFrontMetrics,
+			cloudFrontMetric{"CacheHitRate", "Average", "Percent"},
+			cloudFrontMetric{"OriginLatency", "Average", "Milliseconds"},
+			cloudFrontMetric{"TotalErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"401ErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"403ErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"404ErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"502ErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"503ErrorRate", "Average", "Percent"},
+			cloudFrontMetric{"504ErrorRate", "Average", "Percent"},
+		)
 	}
 
 	for _, metric := range cloudFrontMetrics {
@@ -39,6 +56,10 @@ func CloudFrontMetrics(ctx context.Context, cwClient *cloudwatch.Client, distrib
 					Name:  aws.String("DistributionId"),
 					Value: aws.String(distributionID),
 				},
+				{
+					Name:  aws.String("Region"),
+					Value: aws.String("Global"),
+				},
 			},
 			StartTime:  aws.Time(timeParams["startTime"]),
 			EndTime:    aws.Time(timeParams["endTime"]),
@@ -46,10 +67,6 @@ func CloudFrontMetrics(ctx context.Context, cwClient *cloudwatch.Client, distrib
 			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
 		}
 
-		if metric.Unit != "" {
-			input.Unit = types.StandardUnit(metric.Unit)
-		}
-
 		result, err := cwClient.GetMetricStatistics(ctx, input)
 		if err != nil {
 			return nil, fmt.Errorf("error getting %s: %v", metric.Name, err)
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctTypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"go.uber.org/zap"
+)
+
+// Notable events by where LookupEvents finds them. IAM and console sign-in
+// events are global and recorded in us-east-1.
+var (
+	cloudTrailGlobalEvents = []string{
+		"ConsoleLogin",
+		"CreateAccessKey", "UpdateAccessKey", "DeleteAccessKey",
+		"CreateUser", "DeleteUser", "CreateLoginProfile", "UpdateLoginProfile",
+		"AttachUserPolicy", "AttachRolePolicy", "AttachGroupPolicy",
+		"DetachUserPolicy", "DetachRolePolicy", "DetachGroupPolicy",
+		"PutUserPolicy", "PutRolePolicy", "PutGroupPolicy",
+		"CreatePolicy", "CreatePolicyVersion", "DeletePolicy",
+	}
+	cloudTrailRegionalEvents = []string{
+		"AuthorizeSecurityGroupIngress", "RevokeSecurityGroupIngress",
+		"StopLogging", "DeleteTrail", "UpdateTrail",
+	}
+)
+
+// LookupEvents is limited to 2 requests per second per account and region
+const cloudTrailLookupInterval = 500 * time.Millisecond
+
+// Fields of a CloudTrail record used in the digest
+type cloudTrailRecord struct {
+	EventTime    time.Time `json:"eventTime"`
+	EventName    string    `json:"eventName"`
+	AWSRegion    string    `json:"awsRegion"`
+	SourceIP     string    `json:"sourceIPAddress"`
+	ErrorCode    string    `json:"errorCode"`
+	UserIdentity struct {
+		Type     string `json:"type"`
+		ARN      string `json:"arn"`
+		UserName string `json:"userName"`
+	} `json:"userIdentity"`
+	RequestParameters map[string]any `json:"requestParameters"`
+	ResponseElements  map[string]any `json:"responseElements"`
+}
+
+// CloudTrailEvents looks up the notable management events of the report
+// window with LookupEvents, using regionalClient for security group and
+// trail changes and globalClient (us-east-1) for IAM and sign-in events.
+func CloudTrailEvents(ctx context.Context, regionalClient, globalClient *cloudtrail.Client, timeParams map[string]time.Time) ([]utils.CloudTrailEvent, error) {
+	lookups := []struct {
+		Client     *cloudtrail.Client
+		EventNames []string
+	}{
+		{globalClient, cloudTrailGlobalEvents},
+		{regionalClient, cloudTrailRegionalEvents},
+	}
+
+	var events []utils.CloudTrailEvent
+	throttle := time.NewTicker(cloudTrailLookupInterval)
+	defer throttle.Stop()
+
+	for _, lookup := range lookups {
+		for _, eventName := range lookup.EventNames {
+			input := &cloudtrail.LookupEventsInput{
+				LookupAttributes: []ctTypes.LookupAttribute{
+					{
+						AttributeKey:   ctTypes.LookupAttributeKeyEventName,
+						AttributeValue: aws.String(eventName),
+					},
+				},
+				StartTime: aws.Time(timeParams["startTime"]),
+				EndTime:   aws.Time(timeParams["endTime"]),
+			}
+
+			paginator := cloudtrail.NewLookupEventsPaginator(lookup.Client, input)
+			for paginator.HasMorePages() {
+				<-throttle.C
+				output, err := paginator.NextPage(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to look up %s events: %w", eventName, err)
+				}
+
+				for _, event := range output.Events {
+					if notable, ok := parseCloudTrailRecord(aws.ToString(event.CloudTrailEvent)); ok {
+						events = append(events, notable)
+					}
+				}
+			}
+		}
+	}
+
+	return sortCloudTrailEvents(events), nil
+}
+
+// CloudTrailLogGroupEvents finds the same notable events, plus any root user
+// activity, in the log group a trail delivers to with a Logs Insights query.
+// A multi-region trail covers every region in a single query.
+func CloudTrailLogGroupEvents(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupName string, timeParams map[string]time.Time) ([]utils.CloudTrailEvent, error) {
+	eventNames := append(append([]string{}, cloudTrailGlobalEvents...), cloudTrailRegionalEvents...)
+	query := fmt.Sprintf(`fields @message
+| filter eventName in ["%s"] or userIdentity.type = "Root"
+| sort @timestamp desc
+| limit 1000`, strings.Join(eventNames, `", "`))
+
+	results, err := runInsightsQuery(ctx, logsClient, []string{logGroupName}, query, timeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []utils.CloudTrailEvent
+	for _, row := range results {
+		if notable, ok := parseCloudTrailRecord(resultFields(row)["@message"]); ok {
+			events = append(events, notable)
+		}
+	}
+
+	return sortCloudTrailEvents(events), nil
+}
+
+// Helper function to turn a CloudTrail record into a digest entry. Console
+// sign-ins are only notable when they fail or use the root user.
+func parseCloudTrailRecord(raw string) (utils.CloudTrailEvent, bool) {
+	var record cloudTrailRecord
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		utils.Logger.Error("Failed to parse CloudTrail record", zap.Error(err))
+		return utils.CloudTrailEvent{}, false
+	}
+
+	root := record.UserIdentity.Type == "Root"
+	consoleLoginResult, _ := record.ResponseElements["ConsoleLogin"].(string)
+	if record.EventName == "ConsoleLogin" && !root && consoleLoginResult != "Failure" {
+		return utils.CloudTrailEvent{}, false
+	}
+
+	event := utils.CloudTrailEvent{
+		Time:      record.EventTime,
+		EventName: record.EventName,
+		User:      record.UserIdentity.ARN,
+		SourceIP:  record.SourceIP,
+		Region:    record.AWSRegion,
+		Root:      root,
+		Failed:    record.ErrorCode != "" || consoleLoginResult == "Failure",
+	}
+	if event.User == "" {
+		event.User = record.UserIdentity.UserName
+	}
+
+	// The first identifying request parameter names what was changed
+	for _, key := range []string{"groupId", "userName", "roleName", "groupName", "policyArn", "policyName", "name", "accessKeyId"} {
+		if value, ok := record.RequestParameters[key].(string); ok && value != "" {
+			event.Target = value
+			break
+		}
+	}
+
+	return event, true
+}
+
+// Helper function to sort the events most recent first
+func sortCloudTrailEvents(events []utils.CloudTrailEvent) []utils.CloudTrailEvent {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.After(events[j].Time)
+	})
+	return events
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+	"time"
+)
+
+func TestParseCloudTrailRecord(t *testing.T) {
+	eventTime := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		raw    string
+		want   utils.CloudTrailEvent
+		wantOK bool
+	}{
+		{
+			name: "security group ingress",
+			raw: `{"eventTime":"2024-05-01T09:30:00Z","eventName":"AuthorizeSecurityGroupIngress","awsRegion":"eu-west-1",
+				"sourceIPAddress":"203.0.113.10","userIdentity":{"type":"IAMUser","arn":"arn:aws:iam::123456789012:user/alice","userName":"alice"},
+				"requestParameters":{"groupId":"sg-0123456789abcdef0"}}`,
+			want: utils.CloudTrailEvent{
+				Time:      eventTime,
+				EventName: "AuthorizeSecurityGroupIngress",
+				User:      "arn:aws:iam::123456789012:user/alice",
+				SourceIP:  "203.0.113.10",
+				Region:    "eu-west-1",
+				Target:    "sg-0123456789abcdef0",
+			},
+			wantOK: true,
+		},
+		{
+			name: "failed call falls back to userName",
+			raw: `{"eventTime":"2024-05-01T09:30:00Z","eventName":"AttachRolePolicy","awsRegion":"us-east-1",
+				"errorCode":"AccessDenied","userIdentity":{"type":"IAMUser","userName":"bob"},
+				"requestParameters":{"roleName":"deploy","policyArn":"arn:aws:iam::aws:policy/AdministratorAccess"}}`,
+			want: utils.CloudTrailEvent{
+				Time:      eventTime,
+				EventName: "AttachRolePolicy",
+				User:      "bob",
+				Region:    "us-east-1",
+				Target:    "deploy",
+				Failed:    true,
+			},
+			wantOK: true,
+		},
+		{
+			name: "root console sign-in",
+			raw: `{"eventTime":"2024-05-01T09:30:00Z","eventName":"ConsoleLogin","awsRegion":"us-east-1",
+				"userIdentity":{"type":"Root","arn":"arn:aws:iam::123456789012:root"},
+				"responseElements":{"ConsoleLogin":"Success"}}`,
+			want: utils.CloudTrailEvent{
+				Time:      eventTime,
+				EventName: "ConsoleLogin",
+				User:      "arn:aws:iam::123456789012:root",
+				Region:    "us-east-1",
+				Root:      true,
+			},
+			wantOK: true,
+		},
+		{
+			name: "failed console sign-in",
+			raw: `{"eventTime":"2024-05-01T09:30:00Z","eventName":"ConsoleLogin","awsRegion":"us-east-1",
+				"userIdentity":{"type":"IAMUser","arn":"arn:aws:iam::123456789012:user/alice"},
+				"responseElements":{"ConsoleLogin":"Failure"}}`,
+			want: utils.CloudTrailEvent{
+				Time:      eventTime,
+				EventName: "ConsoleLogin",
+				User:      "arn:aws:iam::123456789012:user/alice",
+				Region:    "us-east-1",
+				Failed:    true,
+			},
+			wantOK: true,
+		},
+		{
+			name: "successful console sign-in is skipped",
+			raw: `{"eventTime":"2024-05-01T09:30:00Z","eventName":"ConsoleLogin",
+				"userIdentity":{"type":"IAMUser","arn":"arn:aws:iam::123456789012:user/alice"},
+				"responseElements":{"ConsoleLogin":"Success"}}`,
+		},
+		{
+			name: "invalid JSON",
+			raw:  `{"eventName":`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCloudTrailRecord(tt.raw)
+			if ok != tt.wantOK {
+				t.Fatalf("parseCloudTrailRecord() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCloudTrailRecord() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortCloudTrailEvents(t *testing.T) {
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	events := []utils.CloudTrailEvent{
+		{Time: base, EventName: "oldest"},
+		{Time: base.Add(2 * time.Hour), EventName: "newest"},
+		{Time: base.Add(time.Hour), EventName: "middle"},
+	}
+
+	var got []string
+	for _, event := range sortCloudTrailEvents(events) {
+		got = append(got, event.EventName)
+	}
+	if want := []string{"newest", "middle", "oldest"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sortCloudTrailEvents() = %v, want %v", got, want)
+	}
+}
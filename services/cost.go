@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	ceTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"go.uber.org/zap"
+)
+
+// CostMetrics reports yesterday's spend, the month-to-date spend against the
+// same days of the previous month, the month-end forecast and the top
+// services (or values of groupByTag) by month-to-date cost. Cost Explorer
+// data is per UTC day and lags a few hours, so today is never included.
+func CostMetrics(ctx context.Context, ceClient *costexplorer.Client, groupByTag string, topN int, now time.Time) (*utils.CostReport, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	previousMonthStart := monthStart.AddDate(0, -1, 0)
+
+	report := &utils.CostReport{
+		Currency: "USD",
+		GroupBy:  "Service",
+	}
+
+	yesterday, currency, err := getCostTotal(ctx, ceClient, today.AddDate(0, 0, -1), today)
+	if err != nil {
+		return nil, err
+	}
+	report.Yesterday = yesterday
+	if currency != "" {
+		report.Currency = currency
+	}
+
+	// On the first day of the month there is nothing to compare yet
+	if today.After(monthStart) {
+		report.MonthToDate, _, err = getCostTotal(ctx, ceClient, monthStart, today)
+		if err != nil {
+			return nil, err
+		}
+
+		report.PreviousMonthToDate, _, err = getCostTotal(ctx, ceClient, previousMonthStart, previousMonthToDateEnd(today))
+		if err != nil {
+			return nil, err
+		}
+
+		groupBy := ceTypes.GroupDefinition{
+			Type: ceTypes.GroupDefinitionTypeDimension,
+			Key:  aws.String("SERVICE"),
+		}
+		if groupByTag != "" {
+			groupBy = ceTypes.GroupDefinition{
+				Type: ceTypes.GroupDefinitionTypeTag,
+				Key:  aws.String(groupByTag),
+			}
+			report.GroupBy = groupByTag
+		}
+
+		report.Top, err = getTopCosts(ctx, ceClient, monthStart, today, groupBy, topN)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Forecasts need some history, so new accounts return DataUnavailableException
+	forecastOutput, err := ceClient.GetCostForecast(ctx, &costexplorer.GetCostForecastInput{
+		TimePeriod: &ceTypes.DateInterval{
+			Start: aws.String(today.Format(time.DateOnly)),
+			End:   aws.String(monthStart.AddDate(0, 1, 0).Format(time.DateOnly)),
+		},
+		Metric:      ceTypes.MetricUnblendedCost,
+		Granularity: ceTypes.GranularityMonthly,
+	})
+	if err != nil {
+		utils.Logger.Error("Failed to get cost forecast", zap.Error(err))
+	} else if forecastOutput.Total != nil {
+		remaining, _ := strconv.ParseFloat(aws.ToString(forecastOutput.Total.Amount), 64)
+		report.Forecast = report.MonthToDate + remaining
+		report.HasForecast = true
+	}
+
+	return report, nil
+}
+
+// Helper function to get the unblended cost between start (inclusive) and end (exclusive)
+func getCostTotal(ctx context.Context, ceClient *costexplorer.Client, start, end time.Time) (float64, string, error) {
+	output, err := ceClient.GetCostAndUsage(ctx, &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &ceTypes.DateInterval{
+			Start: aws.String(start.Format(time.DateOnly)),
+			End:   aws.String(end.Format(time.DateOnly)),
+		},
+		Granularity: ceTypes.GranularityMonthly,
+		Metrics:     []string{"UnblendedCost"},
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get cost from %s to %s: %w", start.Format(time.DateOnly), end.Format(time.DateOnly), err)
+	}
+
+	var total float64
+	var currency string
+	for _, result := range output.ResultsByTime {
+		metric, ok := result.Total["UnblendedCost"]
+		if !ok {
+			continue
+		}
+		amount, _ := strconv.ParseFloat(aws.ToString(metric.Amount), 64)
+		total += amount
+		currency = aws.ToString(metric.Unit)
+	}
+
+	return total, currency, nil
+}
+
+// Helper function to get the highest costs between start and end for a group definition
+func getTopCosts(ctx context.Context, ceClient *costexplorer.Client, start, end time.Time, groupBy ceTypes.GroupDefinition, topN int) ([]utils.CostItem, error) {
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &ceTypes.DateInterval{
+			Start: aws.String(start.Format(time.DateOnly)),
+			End:   aws.String(end.Format(time.DateOnly)),
+		},
+		Granularity: ceTypes.GranularityMonthly,
+		Metrics:     []string{"UnblendedCost"},
+		GroupBy:     []ceTypes.GroupDefinition{groupBy},
+	}
+
+	costs := map[string]float64{}
+	for {
+		output, err := ceClient.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost by %s: %w", aws.ToString(groupBy.Key), err)
+		}
+
+		for _, result := range output.ResultsByTime {
+			for _, group := range result.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+
+				name := costGroupName(group.Keys[0], groupBy.Type == ceTypes.GroupDefinitionTypeTag)
+				amount, _ := strconv.ParseFloat(aws.ToString(group.Metrics["UnblendedCost"].Amount), 64)
+				costs[name] += amount
+			}
+		}
+
+		if output.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = output.NextPageToken
+	}
+
+	return topCostItems(costs, topN), nil
+}
+
+// Helper function to get the exclusive end of the previous month's window
+// covering as many days as the current month to date, capped at its length
+func previousMonthToDateEnd(today time.Time) time.Time {
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	previousEnd := monthStart.AddDate(0, -1, today.Day()-1)
+	if previousEnd.After(monthStart) {
+		return monthStart
+	}
+	return previousEnd
+}
+
+// Helper function to get the name of a cost group. Tag groups have the form
+// key$value, with an empty value when untagged.
+func costGroupName(key string, isTag bool) string {
+	if !isTag {
+		return key
+	}
+	name := key[strings.Index(key, "$")+1:]
+	if name == "" {
+		return "(untagged)"
+	}
+	return name
+}
+
+// Helper function to sort the costs by amount, then name, keeping the topN highest
+func topCostItems(costs map[string]float64, topN int) []utils.CostItem {
+	items := make([]utils.CostItem, 0, len(costs))
+	for name, amount := range costs {
+		items = append(items, utils.CostItem{Name: name, Amount: amount})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Amount != items[j].Amount {
+			return items[i].Amount > items[j].Amount
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	if len(items) > topN {
+		items = items[:topN]
+	}
+	return items
+}
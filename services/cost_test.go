@@ -0,0 +1,87 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+	"time"
+)
+
+func TestPreviousMonthToDateEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		today time.Time
+		want  time.Time
+	}{
+		{"same days of the previous month", time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{"previous year", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"capped at a shorter month", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"whole shorter month", time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previousMonthToDateEnd(tt.today); !got.Equal(tt.want) {
+				t.Errorf("previousMonthToDateEnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostGroupName(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		isTag bool
+		want  string
+	}{
+		{"service", "Amazon Simple Storage Service", false, "Amazon Simple Storage Service"},
+		{"tag value", "project$checkout", true, "checkout"},
+		{"untagged", "project$", true, "(untagged)"},
+		{"value with a dollar sign", "project$a$b", true, "a$b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := costGroupName(tt.key, tt.isTag); got != tt.want {
+				t.Errorf("costGroupName(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopCostItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		costs map[string]float64
+		topN  int
+		want  []utils.CostItem
+	}{
+		{
+			name:  "sorted by amount then name",
+			costs: map[string]float64{"EC2": 12.5, "S3": 3, "RDS": 12.5},
+			topN:  5,
+			want:  []utils.CostItem{{Name: "EC2", Amount: 12.5}, {Name: "RDS", Amount: 12.5}, {Name: "S3", Amount: 3}},
+		},
+		{
+			name:  "limited to topN",
+			costs: map[string]float64{"EC2": 12.5, "S3": 3, "RDS": 8},
+			topN:  2,
+			want:  []utils.CostItem{{Name: "EC2", Amount: 12.5}, {Name: "RDS", Amount: 8}},
+		},
+		{
+			name:  "no costs",
+			costs: map[string]float64{},
+			topN:  5,
+			want:  []utils.CostItem{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topCostItems(tt.costs, tt.topN); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("topCostItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,32 +3,36 @@ package services
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"telegraws/utils"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"go.uber.org/zap"
 )
 
-func CWAgentMetrics(ctx context.Context, cwClient *cloudwatch.Client, instanceID string, timeParams map[string]time.Time) (map[string]float64, error) {
-	metrics := map[string]float64{}
+// Dimensions the agent can add with append_dimensions, besides InstanceId
+var cwAgentAppendDimensions = []string{"ImageId", "InstanceType", "AutoScalingGroupName"}
+
+func CWAgentMetrics(ctx context.Context, cwClient *cloudwatch.Client, instanceID string, processNames, appendDimensions []string, timeParams map[string]time.Time) (*utils.CWAgentInstance, error) {
+	instance := &utils.CWAgentInstance{
+		InstanceID: instanceID,
+		Metrics:    map[string]float64{},
+	}
+
 	period := aws.Int32(3600)
 	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
 		period = aws.Int32(86400)
 	}
 
-	// Memory metrics (average and maximum)
-	memMetrics := []string{"Average", "Maximum"}
-	for _, stat := range memMetrics {
+	getStatistic := func(metricName string, dimensions []types.Dimension, stat string) (float64, error) {
 		input := &cloudwatch.GetMetricStatisticsInput{
 			Namespace:  aws.String("CWAgent"),
-			MetricName: aws.String("mem_used_percent"),
-			Dimensions: []types.Dimension{
-				{
-					Name:  aws.String("InstanceId"),
-					Value: aws.String(instanceID),
-				},
-			},
+			MetricName: aws.String(metricName),
+			Dimensions: dimensions,
 			StartTime:  aws.Time(timeParams["startTime"]),
 			EndTime:    aws.Time(timeParams["endTime"]),
 			Period:     period,
@@ -37,117 +41,241 @@ func CWAgentMetrics(ctx context.Context, cwClient *cloudwatch.Client, instanceID
 
 		result, err := cwClient.GetMetricStatistics(ctx, input)
 		if err != nil {
-			return nil, fmt.Errorf("error getting mem_used_percent (%s): %v", stat, err)
+			return 0, fmt.Errorf("error getting %s (%s): %v", metricName, stat, err)
 		}
 
-		metricKey := fmt.Sprintf("mem_used_percent_%s", stat)
-		if len(result.Datapoints) > 0 {
-			if stat == "Average" {
-				metrics[metricKey] = *result.Datapoints[0].Average
-			} else {
-				metrics[metricKey] = *result.Datapoints[0].Maximum
-			}
-		} else {
-			metrics[metricKey] = 0.0
+		if len(result.Datapoints) == 0 {
+			return 0, nil
 		}
+		switch stat {
+		case "Average":
+			return aws.ToFloat64(result.Datapoints[0].Average), nil
+		case "Maximum":
+			return aws.ToFloat64(result.Datapoints[0].Maximum), nil
+		case "Minimum":
+			return aws.ToFloat64(result.Datapoints[0].Minimum), nil
+		}
+		return 0, nil
 	}
 
-	// Disk metrics (with proper dimensions)
-	// First, discover the device and fstype dimensions
-	listInput := &cloudwatch.ListMetricsInput{
-		Namespace:  aws.String("CWAgent"),
-		MetricName: aws.String("disk_used_percent"),
-		Dimensions: []types.DimensionFilter{
+	// Memory and swap metrics (average and maximum). The agent may append
+	// extra dimensions (ImageId, InstanceType...), so use the discovered set
+	// matching appendDimensions and fall back to InstanceId alone.
+	for _, metricName := range []string{"mem_used_percent", "swap_used_percent"} {
+		dimensions := []types.Dimension{
 			{
 				Name:  aws.String("InstanceId"),
 				Value: aws.String(instanceID),
 			},
-			{
-				Name:  aws.String("path"),
-				Value: aws.String("/"),
-			},
-		},
-	}
+		}
 
-	listResult, err := cwClient.ListMetrics(ctx, listInput)
-	if err != nil {
-		return nil, fmt.Errorf("error listing disk metrics: %v", err)
-	}
+		listed, err := listCWAgentMetrics(ctx, cwClient, metricName, instanceID)
+		if err != nil {
+			return nil, err
+		}
+		listed = selectCWAgentMetrics(listed, appendDimensions, func(types.Metric) string { return "" })
+		if len(listed) > 0 {
+			dimensions = listed[0].Dimensions
+		}
 
-	var device, fstype string
-	for _, metric := range listResult.Metrics {
-		isCorrectInstance := false
-		for _, dim := range metric.Dimensions {
-			if *dim.Name == "InstanceId" && *dim.Value == instanceID {
-				isCorrectInstance = true
-				break
+		for _, stat := range []string{"Average", "Maximum"} {
+			value, err := getStatistic(metricName, dimensions, stat)
+			if err != nil {
+				return nil, err
 			}
+			instance.Metrics[fmt.Sprintf("%s_%s", metricName, stat)] = value
 		}
+	}
 
-		if !isCorrectInstance {
-			continue
+	// Disk metrics for every mounted path reported by the agent
+	disks := map[string]*utils.CWAgentDisk{}
+	for _, metricName := range []string{"disk_used_percent", "disk_inodes_used", "disk_inodes_total"} {
+		listed, err := listCWAgentMetrics(ctx, cwClient, metricName, instanceID)
+		if err != nil {
+			return nil, err
 		}
+		listed = selectCWAgentMetrics(listed, appendDimensions, func(metric types.Metric) string {
+			return dimensionValue(metric.Dimensions, "path")
+		})
+
+		for _, metric := range listed {
+			path := dimensionValue(metric.Dimensions, "path")
+			if path == "" {
+				continue
+			}
+
+			disk, ok := disks[path]
+			if !ok {
+				disk = &utils.CWAgentDisk{
+					Path:   path,
+					Device: dimensionValue(metric.Dimensions, "device"),
+					FSType: dimensionValue(metric.Dimensions, "fstype"),
+				}
+				disks[path] = disk
+			}
 
-		for _, dim := range metric.Dimensions {
-			if dim.Name == nil {
+			value, err := getStatistic(metricName, metric.Dimensions, "Average")
+			if err != nil {
+				utils.Logger.Error("Failed to get CloudWatch Agent disk metric",
+					zap.Error(err),
+					zap.String("instanceID", instanceID),
+					zap.String("path", path),
+				)
 				continue
 			}
 
-			switch *dim.Name {
-			case "device":
-				if dim.Value != nil {
-					device = *dim.Value
+			switch metricName {
+			case "disk_used_percent":
+				disk.UsedPercent = value
+			case "disk_inodes_used":
+				disk.InodesUsed = value
+			case "disk_inodes_total":
+				disk.InodesTotal = value
+			}
+		}
+	}
+
+	for _, disk := range disks {
+		instance.Disks = append(instance.Disks, *disk)
+	}
+	sort.Slice(instance.Disks, func(i, j int) bool {
+		return instance.Disks[i].Path < instance.Disks[j].Path
+	})
+
+	// procstat metrics for the configured processes, matched on the exe or
+	// pattern dimension depending on how the agent looks them up
+	if len(processNames) > 0 {
+		processes := map[string]*utils.CWAgentProcess{}
+		for _, name := range processNames {
+			processes[name] = &utils.CWAgentProcess{Name: name}
+		}
+
+		procstatMetrics := []struct {
+			Name      string
+			Statistic string
+		}{
+			{"procstat_lookup_pid_count", "Minimum"},
+			{"procstat_cpu_usage", "Average"},
+			{"procstat_memory_rss", "Average"},
+		}
+
+		for _, procstatMetric := range procstatMetrics {
+			listed, err := listCWAgentMetrics(ctx, cwClient, procstatMetric.Name, instanceID)
+			if err != nil {
+				return nil, err
+			}
+			listed = selectCWAgentMetrics(listed, appendDimensions, func(metric types.Metric) string {
+				return dimensionValue(metric.Dimensions, "exe") + "|" + dimensionValue(metric.Dimensions, "pattern")
+			})
+
+			for _, metric := range listed {
+				name := dimensionValue(metric.Dimensions, "exe")
+				if name == "" {
+					name = dimensionValue(metric.Dimensions, "pattern")
 				}
-			case "fstype":
-				if dim.Value != nil {
-					fstype = *dim.Value
+				process, ok := processes[name]
+				if !ok {
+					continue
+				}
+
+				value, err := getStatistic(procstatMetric.Name, metric.Dimensions, procstatMetric.Statistic)
+				if err != nil {
+					utils.Logger.Error("Failed to get CloudWatch Agent procstat metric",
+						zap.Error(err),
+						zap.String("instanceID", instanceID),
+						zap.String("process", name),
+					)
+					continue
+				}
+
+				process.Found = true
+				switch procstatMetric.Name {
+				case "procstat_lookup_pid_count":
+					process.PIDCount = value
+				case "procstat_cpu_usage":
+					process.CPUPercent = value
+				case "procstat_memory_rss":
+					process.MemoryMB = value / (1024.0 * 1024.0)
 				}
 			}
 		}
 
-		if device != "" && fstype != "" {
-			break
+		for _, name := range processNames {
+			instance.Processes = append(instance.Processes, *processes[name])
 		}
 	}
 
-	// Get disk_used_percent metric with the discovered dimensions
-	diskInput := &cloudwatch.GetMetricStatisticsInput{
+	return instance, nil
+}
+
+// Helper function to list the CWAgent metrics of an instance with all their dimensions
+func listCWAgentMetrics(ctx context.Context, cwClient *cloudwatch.Client, metricName, instanceID string) ([]types.Metric, error) {
+	listInput := &cloudwatch.ListMetricsInput{
 		Namespace:  aws.String("CWAgent"),
-		MetricName: aws.String("disk_used_percent"),
-		Dimensions: []types.Dimension{
+		MetricName: aws.String(metricName),
+		Dimensions: []types.DimensionFilter{
 			{
 				Name:  aws.String("InstanceId"),
 				Value: aws.String(instanceID),
 			},
-			{
-				Name:  aws.String("path"),
-				Value: aws.String("/"),
-			},
-			{
-				Name:  aws.String("device"),
-				Value: aws.String(device),
-			},
-			{
-				Name:  aws.String("fstype"),
-				Value: aws.String(fstype),
-			},
 		},
-		StartTime:  aws.Time(timeParams["startTime"]),
-		EndTime:    aws.Time(timeParams["endTime"]),
-		Period:     period,
-		Statistics: []types.Statistic{types.Statistic("Average")},
 	}
 
-	diskResult, err := cwClient.GetMetricStatistics(ctx, diskInput)
-	if err != nil {
-		return nil, fmt.Errorf("error getting disk_used_percent: %v", err)
+	var metrics []types.Metric
+	paginator := cloudwatch.NewListMetricsPaginator(cwClient, listInput)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s metrics: %v", metricName, err)
+		}
+		metrics = append(metrics, output.Metrics...)
 	}
 
-	if len(diskResult.Datapoints) > 0 {
-		metrics["disk_used_percent"] = *diskResult.Datapoints[0].Average
-	} else {
-		metrics["disk_used_percent"] = 0.0
+	return metrics, nil
+}
+
+// Helper function to keep a single dimension set per series, identified by
+// key, when the agent publishes a metric both with and without its
+// append_dimensions. The set with exactly the configured appendDimensions
+// wins, otherwise the one with the fewest dimensions.
+func selectCWAgentMetrics(listed []types.Metric, appendDimensions []string, key func(types.Metric) string) []types.Metric {
+	matches := func(metric types.Metric) bool {
+		for _, name := range cwAgentAppendDimensions {
+			present := dimensionValue(metric.Dimensions, name) != ""
+			if present != slices.Contains(appendDimensions, name) {
+				return false
+			}
+		}
+		return true
 	}
 
-	return metrics, nil
+	var keys []string
+	selected := make(map[string]types.Metric)
+	for _, metric := range listed {
+		k := key(metric)
+		current, ok := selected[k]
+		switch {
+		case !ok:
+			keys = append(keys, k)
+			selected[k] = metric
+		case matches(current):
+		case matches(metric) || len(metric.Dimensions) < len(current.Dimensions):
+			selected[k] = metric
+		}
+	}
+
+	metrics := make([]types.Metric, 0, len(keys))
+	for _, k := range keys {
+		metrics = append(metrics, selected[k])
+	}
+	return metrics
+}
+
+func dimensionValue(dimensions []types.Dimension, name string) string {
+	for _, dim := range dimensions {
+		if aws.ToString(dim.Name) == name {
+			return aws.ToString(dim.Value)
+		}
+	}
+	return ""
 }
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// cwAgentMetric builds a listed metric from name/value dimension pairs
+func cwAgentMetric(pairs ...string) types.Metric {
+	metric := types.Metric{MetricName: aws.String("disk_used_percent")}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		metric.Dimensions = append(metric.Dimensions, types.Dimension{
+			Name:  aws.String(pairs[i]),
+			Value: aws.String(pairs[i+1]),
+		})
+	}
+	return metric
+}
+
+func TestSelectCWAgentMetrics(t *testing.T) {
+	plainRoot := cwAgentMetric("InstanceId", "i-1", "path", "/")
+	appendedRoot := cwAgentMetric("InstanceId", "i-1", "ImageId", "ami-1", "InstanceType", "t3.micro", "path", "/")
+	typedRoot := cwAgentMetric("InstanceId", "i-1", "InstanceType", "t3.micro", "path", "/")
+	plainData := cwAgentMetric("InstanceId", "i-1", "path", "/data")
+	byPath := func(metric types.Metric) string { return dimensionValue(metric.Dimensions, "path") }
+
+	tests := []struct {
+		name             string
+		listed           []types.Metric
+		appendDimensions []string
+		want             []types.Metric
+	}{
+		{
+			name:   "fewest dimensions without appendDimensions",
+			listed: []types.Metric{appendedRoot, plainRoot, plainData},
+			want:   []types.Metric{plainRoot, plainData},
+		},
+		{
+			name:             "exact appendDimensions match wins",
+			listed:           []types.Metric{plainRoot, typedRoot, appendedRoot},
+			appendDimensions: []string{"ImageId", "InstanceType"},
+			want:             []types.Metric{appendedRoot},
+		},
+		{
+			name:             "exact match is kept over fewer dimensions",
+			listed:           []types.Metric{typedRoot, plainRoot},
+			appendDimensions: []string{"InstanceType"},
+			want:             []types.Metric{typedRoot},
+		},
+		{
+			name:             "fewest dimensions when nothing matches",
+			listed:           []types.Metric{appendedRoot, typedRoot},
+			appendDimensions: []string{"AutoScalingGroupName"},
+			want:             []types.Metric{typedRoot},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectCWAgentMetrics(tt.listed, tt.appendDimensions, byPath)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectCWAgentMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
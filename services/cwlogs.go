@@ -2,31 +2,242 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"telegraws/config"
 	"telegraws/utils"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"go.uber.org/zap"
 )
 
-func CWLogs(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupName string, timeParams map[string]time.Time) (map[string]int, error) {
-	levels := map[string]string{
-		"error": "{ $.level = \"error\" }",
-		"warn":  "{ $.level = \"warn\" }",
-		"info":  "{ $.level = \"info\" }",
+const (
+	// Logs Insights accepts at most 50 log groups per query
+	insightsMaxLogGroups = 50
+	// Polling stops this long before the Lambda deadline so the fallback
+	// and the notification still have time to run
+	insightsDeadlineMargin = 30 * time.Second
+	// Maximum wait when the context has no deadline (local runs)
+	insightsMaxWait = 60 * time.Second
+	// Layout of timestamps returned by Logs Insights, in UTC
+	insightsTimeLayout = "2006-01-02 15:04:05.000"
+)
+
+// Variable parts replaced when fingerprinting log messages, most specific first
+var fingerprintReplacements = []struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-fA-F]{8,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+}
+
+// Filter pattern clauses with a Logs Insights equivalent
+var (
+	jsonFilterClause = regexp.MustCompile(`^\$\.([\w.]+)\s*(=|!=)\s*(?:"([^"*]*)"|([\w.-]+))$`)
+	termFilterClause = regexp.MustCompile(`^([?-]?)(?:"([^"]+)"|([^\s"{}()\[\]*]+))$`)
+	termFilterTokens = regexp.MustCompile(`[?-]?"[^"]*"|\S+`)
+)
+
+// DiscoverLogGroups returns the log groups matching any of the glob patterns
+// and none of the exclude patterns. Each pattern is listed by its literal
+// prefix, so patterns should start with a fixed path such as /aws/lambda/.
+// On error the groups found so far are returned along with the error.
+func DiscoverLogGroups(ctx context.Context, logsClient *cloudwatchlogs.Client, patterns, excludePatterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var logGroupNames []string
+	var errs []error
+
+	for _, pattern := range patterns {
+		input := &cloudwatchlogs.DescribeLogGroupsInput{}
+		if prefix := pattern[:strings.IndexAny(pattern+"*", "*?[\\")]; prefix != "" {
+			input.LogGroupNamePrefix = aws.String(prefix)
+		}
+
+		paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(logsClient, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to describe log groups for %s: %w", pattern, err))
+				break
+			}
+
+			for _, logGroup := range output.LogGroups {
+				name := aws.ToString(logGroup.LogGroupName)
+				if seen[name] || !matchesAny(name, []string{pattern}) || matchesAny(name, excludePatterns) {
+					continue
+				}
+				seen[name] = true
+				logGroupNames = append(logGroupNames, name)
+			}
+		}
+	}
+
+	sort.Strings(logGroupNames)
+	return logGroupNames, errors.Join(errs...)
+}
+
+// Helper function to match a log group name against glob patterns. The
+// patterns are validated when the configuration is loaded.
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CWLogsInsights counts the events by level of every log group using the
+// default counters with a single `stats count() by level` Logs Insights query.
+// Groups with custom counters are skipped and must be counted with CWLogs.
+// On error the caller should fall back to CWLogs for all groups.
+func CWLogsInsights(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroups []config.LogGroupConfig, timeParams map[string]time.Time) (map[string]map[string]int, error) {
+	counts := make(map[string]map[string]int)
+	var logGroupNames []string
+	for _, logGroup := range logGroups {
+		if !logGroup.HasDefaultCounters() {
+			continue
+		}
+		counts[logGroup.Name] = make(map[string]int, len(config.DefaultLogCounters))
+		for _, counter := range config.DefaultLogCounters {
+			counts[logGroup.Name][counter.Label] = 0
+		}
+		logGroupNames = append(logGroupNames, logGroup.Name)
+	}
+
+	if len(logGroupNames) == 0 {
+		return counts, nil
+	}
+
+	// Same semantics as the default { $.level = "..." } filter patterns
+	query := `filter level in ["info", "warn", "error"] | stats count(*) as total by @log, level`
+
+	results, err := runInsightsQuery(ctx, logsClient, logGroupNames, query, timeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range results {
+		fields := resultFields(row)
+
+		// @log has the form account-id:log-group-name
+		logGroupName := fields["@log"]
+		if idx := strings.Index(logGroupName, ":"); idx >= 0 {
+			logGroupName = logGroupName[idx+1:]
+		}
+
+		groupCounts, ok := counts[logGroupName]
+		if !ok {
+			continue
+		}
+
+		total, err := strconv.Atoi(fields["total"])
+		if err != nil {
+			continue
+		}
+		groupCounts[strings.ToUpper(fields["level"])] = total
+	}
+
+	return counts, nil
+}
+
+// Helper function to run a Logs Insights query over any number of log groups,
+// polling until the query completes or the deadline is reached
+func runInsightsQuery(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupNames []string, query string, timeParams map[string]time.Time) ([][]logsTypes.ResultField, error) {
+	deadline, ok := ctx.Deadline()
+	if ok {
+		deadline = deadline.Add(-insightsDeadlineMargin)
+	} else {
+		deadline = time.Now().Add(insightsMaxWait)
 	}
 
-	counts := map[string]int{
-		"error": 0,
-		"warn":  0,
-		"info":  0,
+	var queryIDs []string
+	for start := 0; start < len(logGroupNames); start += insightsMaxLogGroups {
+		end := min(start+insightsMaxLogGroups, len(logGroupNames))
+
+		output, err := logsClient.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+			LogGroupNames: logGroupNames[start:end],
+			QueryString:   aws.String(query),
+			StartTime:     aws.Int64(timeParams["startTime"].Unix()),
+			EndTime:       aws.Int64(timeParams["endTime"].Unix()),
+			Limit:         aws.Int32(10000),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to start Logs Insights query: %w", err)
+		}
+		queryIDs = append(queryIDs, aws.ToString(output.QueryId))
 	}
 
-	for level, filterPattern := range levels {
+	var results [][]logsTypes.ResultField
+	for _, queryID := range queryIDs {
+		for {
+			output, err := logsClient.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+				QueryId: aws.String(queryID),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get Logs Insights query results: %w", err)
+			}
+
+			if output.Status == logsTypes.QueryStatusComplete {
+				results = append(results, output.Results...)
+				break
+			}
+
+			if output.Status != logsTypes.QueryStatusScheduled && output.Status != logsTypes.QueryStatusRunning {
+				return nil, fmt.Errorf("query ended with status %s", output.Status)
+			}
+
+			if time.Now().After(deadline) {
+				for _, id := range queryIDs {
+					if _, err := logsClient.StopQuery(ctx, &cloudwatchlogs.StopQueryInput{QueryId: aws.String(id)}); err != nil {
+						utils.Logger.Warn("Failed to stop Logs Insights query",
+							zap.Error(err),
+							zap.String("queryID", id),
+						)
+					}
+				}
+				return nil, fmt.Errorf("query did not complete before the deadline")
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+	}
+
+	return results, nil
+}
+
+func resultFields(row []logsTypes.ResultField) map[string]string {
+	fields := make(map[string]string, len(row))
+	for _, field := range row {
+		fields[aws.ToString(field.Field)] = aws.ToString(field.Value)
+	}
+	return fields
+}
+
+// CWLogs counts the events matching each counter's filter pattern, keyed by label
+func CWLogs(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupName string, counters []config.LogCounter, timeParams map[string]time.Time) (map[string]int, error) {
+	counts := make(map[string]int, len(counters))
+
+	for _, counter := range counters {
 		input := &cloudwatchlogs.FilterLogEventsInput{
 			LogGroupName:  aws.String(logGroupName),
-			FilterPattern: aws.String(filterPattern),
+			FilterPattern: aws.String(counter.Pattern),
 			StartTime:     aws.Int64(timeParams["startTime"].UnixMilli()),
 			EndTime:       aws.Int64(timeParams["endTime"].UnixMilli()),
 		}
@@ -40,17 +251,192 @@ func CWLogs(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupName
 				// Don't fail the whole report for log counting issues
 				utils.Logger.Error("Failed to count logs",
 					zap.Error(err),
-					zap.String("level", level),
+					zap.String("label", counter.Label),
 					zap.String("logGroup", logGroupName),
-					zap.String("filterPattern", filterPattern),
+					zap.String("filterPattern", counter.Pattern),
 				)
 				break
 			}
 			count += len(output.Events)
 		}
 
-		counts[level] = count
+		counts[counter.Label] = count
 	}
 
 	return counts, nil
 }
+
+// CWLogsTopErrors groups the events matching errorPattern by fingerprint and
+// returns the topN most frequent ones. Events are counted by message with a
+// Logs Insights query, at most 10000 distinct messages, and the messages are
+// then merged by fingerprint.
+func CWLogsTopErrors(ctx context.Context, logsClient *cloudwatchlogs.Client, logGroupName, errorPattern string, topN int, timeParams map[string]time.Time) ([]utils.LogErrorGroup, error) {
+	filter, err := insightsFilter(errorPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON logs are grouped by their message field
+	query := `fields coalesce(message, msg, error, errorMessage, @message) as text
+| stats count(*) as total, min(@timestamp) as firstSeen, max(@timestamp) as lastSeen by text
+| sort total desc
+| limit 10000`
+	if filter != "" {
+		query = "filter " + filter + "\n| " + query
+	}
+
+	results, err := runInsightsQuery(ctx, logsClient, []string{logGroupName}, query, timeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*utils.LogErrorGroup)
+	for _, row := range results {
+		fields := resultFields(row)
+		fingerprint := fingerprintLogMessage(fields["text"])
+		if fingerprint == "" {
+			continue
+		}
+
+		count, err := strconv.Atoi(fields["total"])
+		if err != nil {
+			continue
+		}
+		firstSeen, _ := time.Parse(insightsTimeLayout, fields["firstSeen"])
+		lastSeen, _ := time.Parse(insightsTimeLayout, fields["lastSeen"])
+
+		group, ok := groups[fingerprint]
+		if !ok {
+			group = &utils.LogErrorGroup{
+				Fingerprint: fingerprint,
+				FirstSeen:   firstSeen,
+				LastSeen:    lastSeen,
+			}
+			groups[fingerprint] = group
+		}
+
+		group.Count += count
+		if firstSeen.Before(group.FirstSeen) {
+			group.FirstSeen = firstSeen
+		}
+		if lastSeen.After(group.LastSeen) {
+			group.LastSeen = lastSeen
+		}
+	}
+
+	topErrors := make([]utils.LogErrorGroup, 0, len(groups))
+	for _, group := range groups {
+		topErrors = append(topErrors, *group)
+	}
+	sort.Slice(topErrors, func(i, j int) bool {
+		if topErrors[i].Count != topErrors[j].Count {
+			return topErrors[i].Count > topErrors[j].Count
+		}
+		return topErrors[i].Fingerprint < topErrors[j].Fingerprint
+	})
+
+	if len(topErrors) > topN {
+		topErrors = topErrors[:topN]
+	}
+	return topErrors, nil
+}
+
+// Helper function to translate a CloudWatch Logs filter pattern to a Logs
+// Insights filter. Supported are JSON comparisons joined by && or || (e.g.
+// { $.level = "error" }) and term patterns where every term is required, or
+// every term is optional with ? (e.g. ?ERROR ?CRIT). Terms are case sensitive
+// in both languages.
+func insightsFilter(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(pattern, "{") && strings.HasSuffix(pattern, "}") {
+		body := strings.TrimSpace(pattern[1 : len(pattern)-1])
+		operator := "and"
+		separator := "&&"
+		if strings.Contains(body, "||") {
+			if strings.Contains(body, "&&") {
+				return "", fmt.Errorf("filter pattern %q mixes && and ||, which is not supported", pattern)
+			}
+			operator, separator = "or", "||"
+		}
+
+		var clauses []string
+		for _, clause := range strings.Split(body, separator) {
+			match := jsonFilterClause.FindStringSubmatch(strings.TrimSpace(clause))
+			if match == nil {
+				return "", fmt.Errorf("filter pattern %q has no Logs Insights equivalent", pattern)
+			}
+			value := strconv.Quote(match[3])
+			if match[4] != "" {
+				value = strconv.Quote(match[4])
+				if _, err := strconv.ParseFloat(match[4], 64); err == nil {
+					value = match[4]
+				}
+			}
+			clauses = append(clauses, fmt.Sprintf("%s %s %s", match[1], match[2], value))
+		}
+		return strings.Join(clauses, " "+operator+" "), nil
+	}
+
+	var clauses []string
+	optional := 0
+	for _, token := range termFilterTokens.FindAllString(pattern, -1) {
+		match := termFilterClause.FindStringSubmatch(token)
+		if match == nil {
+			return "", fmt.Errorf("filter pattern %q has no Logs Insights equivalent", pattern)
+		}
+		term := match[2]
+		if match[3] != "" {
+			term = match[3]
+		}
+
+		switch match[1] {
+		case "?":
+			optional++
+			clauses = append(clauses, "@message like "+strconv.Quote(term))
+		case "-":
+			clauses = append(clauses, "@message not like "+strconv.Quote(term))
+		default:
+			clauses = append(clauses, "@message like "+strconv.Quote(term))
+		}
+	}
+
+	switch optional {
+	case 0:
+		return strings.Join(clauses, " and "), nil
+	case len(clauses):
+		return strings.Join(clauses, " or "), nil
+	default:
+		return "", fmt.Errorf("filter pattern %q mixes optional and required terms, which is not supported", pattern)
+	}
+}
+
+// Helper function to reduce a log message to a fingerprint so messages that
+// only differ by IDs, numbers or addresses are grouped together. For JSON
+// logs only the message field is used.
+func fingerprintLogMessage(message string) string {
+	var structured map[string]any
+	if err := json.Unmarshal([]byte(message), &structured); err == nil {
+		for _, key := range []string{"message", "msg", "error", "errorMessage"} {
+			if value, ok := structured[key].(string); ok && value != "" {
+				message = value
+				break
+			}
+		}
+	}
+
+	// Lambda prefixes lines with a timestamp and request ID, which the
+	// replacements below turn into placeholders
+	for _, replacement := range fingerprintReplacements {
+		message = replacement.Pattern.ReplaceAllString(message, replacement.Replacement)
+	}
+
+	message = strings.Join(strings.Fields(message), " ")
+	if runes := []rune(message); len(runes) > 200 {
+		message = string(runes[:200])
+	}
+	return message
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprintLogMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "plain text",
+			message: "connection refused",
+			want:    "connection refused",
+		},
+		{
+			name:    "numbers and addresses",
+			message: "failed to connect to 10.0.0.12:5432 after 3 retries",
+			want:    "failed to connect to <ip> after <n> retries",
+		},
+		{
+			name:    "Lambda prefix",
+			message: "2024-05-01T10:00:00.123Z\t3f2a9c1e-7b4d-4e8f-9a0b-1c2d3e4f5a6b\tERROR\ttimeout",
+			want:    "<n>-<n>-<n>T<n>:<n>:<n>.<n>Z <uuid> ERROR timeout",
+		},
+		{
+			name:    "hex identifiers",
+			message: "object deadbeef01 at 0x7ffe not found",
+			want:    "object <hex> at <hex> not found",
+		},
+		{
+			name:    "JSON message field",
+			message: `{"level":"error","msg":"order 1234 not found","ts":1714557600}`,
+			want:    "order <n> not found",
+		},
+		{
+			name:    "JSON without a message field",
+			message: `{"level":"error","code":500}`,
+			want:    `{"level":"error","code":<n>}`,
+		},
+		{
+			name:    "whitespace collapsed",
+			message: "  too   many\n\topen files  ",
+			want:    "too many open files",
+		},
+		{
+			name:    "truncated to 200 characters",
+			message: strings.Repeat("é", 250),
+			want:    strings.Repeat("é", 200),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprintLogMessage(tt.message); got != tt.want {
+				t.Errorf("fingerprintLogMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsightsFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", pattern: "  ", want: ""},
+		{name: "JSON string", pattern: `{ $.level = "error" }`, want: `level = "error"`},
+		{name: "JSON nested field", pattern: `{ $.error.code != "E42" }`, want: `error.code != "E42"`},
+		{name: "JSON number", pattern: `{ $.status = 500 }`, want: `status = 500`},
+		{name: "JSON unquoted word", pattern: `{ $.level = error }`, want: `level = "error"`},
+		{
+			name:    "JSON and",
+			pattern: `{ $.level = "error" && $.service = "orders" }`,
+			want:    `level = "error" and service = "orders"`,
+		},
+		{
+			name:    "JSON or",
+			pattern: `{ $.level = "error" || $.level = "fatal" }`,
+			want:    `level = "error" or level = "fatal"`,
+		},
+		{name: "JSON mixed operators", pattern: `{ $.a = "1" && $.b = "2" || $.c = "3" }`, wantErr: true},
+		{name: "JSON wildcard", pattern: `{ $.level = "err*" }`, wantErr: true},
+		{name: "JSON numeric comparison", pattern: `{ $.latency > 1000 }`, wantErr: true},
+		{name: "single term", pattern: "ERROR", want: `@message like "ERROR"`},
+		{
+			name:    "required terms",
+			pattern: `ERROR "connection reset" -retrying`,
+			want:    `@message like "ERROR" and @message like "connection reset" and @message not like "retrying"`,
+		},
+		{
+			name:    "optional terms",
+			pattern: "?ERROR ?CRITICAL",
+			want:    `@message like "ERROR" or @message like "CRITICAL"`,
+		},
+		{name: "optional and required terms", pattern: "?ERROR timeout", wantErr: true},
+		{name: "space-delimited pattern", pattern: "[ip, user, status=5*]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := insightsFilter(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("insightsFilter(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("insightsFilter(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	patterns := []string{"/aws/lambda/orders-*", "/ecs/api"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/aws/lambda/orders-prod", true},
+		{"/aws/lambda/orders-", true},
+		{"/ecs/api", true},
+		{"/ecs/api/worker", false},
+		{"/aws/lambda/orders/nested", false},
+		{"/aws/lambda/payments", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesAny(tt.name, patterns); got != tt.want {
+				t.Errorf("matchesAny(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,14 +3,18 @@ package services
 import (
 	"context"
 	"fmt"
+	"telegraws/utils"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
 )
 
-func DynamoDBMetrics(ctx context.Context, cwClient *cloudwatch.Client, timeParams map[string]time.Time, tableName string) (map[string]float64, error) {
+func DynamoDBMetrics(ctx context.Context, cwClient *cloudwatch.Client, ddbClient *dynamodb.Client, timeParams map[string]time.Time, tableName string) (*utils.DynamoDBTable, error) {
 	metrics := map[string]float64{}
 	period := aws.Int32(3600)
 	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
@@ -32,16 +36,18 @@ func DynamoDBMetrics(ctx context.Context, cwClient *cloudwatch.Client, timeParam
 		{"RequestCount", "Sum", "count"},
 	}
 
+	tableDimensions := []types.Dimension{
+		{
+			Name:  aws.String("TableName"),
+			Value: aws.String(tableName),
+		},
+	}
+
 	for _, metric := range dynamoMetrics {
 		input := &cloudwatch.GetMetricStatisticsInput{
 			Namespace:  aws.String("AWS/DynamoDB"),
 			MetricName: aws.String(metric.Name),
-			Dimensions: []types.Dimension{
-				{
-					Name:  aws.String("TableName"),
-					Value: aws.String(tableName),
-				},
-			},
+			Dimensions: tableDimensions,
 			StartTime:  aws.Time(timeParams["startTime"]),
 			EndTime:    aws.Time(timeParams["endTime"]),
 			Period:     period,
@@ -69,5 +75,197 @@ func DynamoDBMetrics(ctx context.Context, cwClient *cloudwatch.Client, timeParam
 		}
 	}
 
-	return metrics, nil
+	table := &utils.DynamoDBTable{
+		TableName: tableName,
+		Metrics:   metrics,
+	}
+
+	// Table metadata is best effort, the CloudWatch metrics above are still reported
+	tableOutput, err := ddbClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		utils.Logger.Error("Failed to describe DynamoDB table",
+			zap.Error(err),
+			zap.String("tableName", tableName),
+		)
+		return table, nil
+	}
+
+	description := tableOutput.Table
+	table.ItemCount = aws.ToInt64(description.ItemCount)
+	table.SizeBytes = aws.ToInt64(description.TableSizeBytes)
+	table.OnDemand = description.BillingModeSummary != nil &&
+		description.BillingModeSummary.BillingMode == ddbTypes.BillingModePayPerRequest
+
+	if !table.OnDemand {
+		getDynamoDBUtilization(ctx, cwClient, tableDimensions, description.ProvisionedThroughput, metrics, *period, timeParams)
+	}
+
+	for _, gsi := range description.GlobalSecondaryIndexes {
+		if gsi.IndexName == nil {
+			continue
+		}
+		table.Indexes = append(table.Indexes, getDynamoDBIndexMetrics(ctx, cwClient, tableName, gsi, table.OnDemand, *period, timeParams))
+	}
+
+	backupsOutput, err := ddbClient.DescribeContinuousBackups(ctx, &dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		utils.Logger.Error("Failed to describe DynamoDB continuous backups",
+			zap.Error(err),
+			zap.String("tableName", tableName),
+		)
+	} else if backupsOutput.ContinuousBackupsDescription != nil &&
+		backupsOutput.ContinuousBackupsDescription.PointInTimeRecoveryDescription != nil {
+		table.PITREnabled = backupsOutput.ContinuousBackupsDescription.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == ddbTypes.PointInTimeRecoveryStatusEnabled
+	}
+
+	listBackupsInput := &dynamodb.ListBackupsInput{
+		TableName:  aws.String(tableName),
+		BackupType: ddbTypes.BackupTypeFilterAll,
+	}
+	for {
+		output, err := ddbClient.ListBackups(ctx, listBackupsInput)
+		if err != nil {
+			utils.Logger.Error("Failed to list DynamoDB backups",
+				zap.Error(err),
+				zap.String("tableName", tableName),
+			)
+			break
+		}
+		for _, backup := range output.BackupSummaries {
+			if created := aws.ToTime(backup.BackupCreationDateTime); created.After(table.LastBackup) {
+				table.LastBackup = created
+			}
+		}
+		if output.LastEvaluatedBackupArn == nil {
+			break
+		}
+		listBackupsInput.ExclusiveStartBackupArn = output.LastEvaluatedBackupArn
+	}
+
+	return table, nil
+}
+
+func getDynamoDBIndexMetrics(ctx context.Context, cwClient *cloudwatch.Client, tableName string, gsi ddbTypes.GlobalSecondaryIndexDescription, onDemand bool, period int32, timeParams map[string]time.Time) utils.DynamoDBIndex {
+	index := utils.DynamoDBIndex{
+		IndexName: *gsi.IndexName,
+		Metrics:   map[string]float64{},
+	}
+
+	indexDimensions := []types.Dimension{
+		{
+			Name:  aws.String("TableName"),
+			Value: aws.String(tableName),
+		},
+		{
+			Name:  aws.String("GlobalSecondaryIndexName"),
+			Value: gsi.IndexName,
+		},
+	}
+
+	for _, metricName := range []string{"ConsumedReadCapacityUnits", "ConsumedWriteCapacityUnits", "ReadThrottleEvents", "WriteThrottleEvents"} {
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/DynamoDB"),
+			MetricName: aws.String(metricName),
+			Dimensions: indexDimensions,
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     aws.Int32(period),
+			Statistics: []types.Statistic{types.StatisticSum},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			utils.Logger.Error("Failed to get DynamoDB index metric",
+				zap.Error(err),
+				zap.String("metricName", metricName),
+				zap.String("tableName", tableName),
+				zap.String("indexName", index.IndexName),
+			)
+			continue
+		}
+
+		if len(result.Datapoints) > 0 {
+			index.Metrics[metricName] = *result.Datapoints[0].Sum
+		} else {
+			index.Metrics[metricName] = 0.0
+		}
+	}
+
+	if !onDemand {
+		getDynamoDBUtilization(ctx, cwClient, indexDimensions, gsi.ProvisionedThroughput, index.Metrics, period, timeParams)
+	}
+
+	return index
+}
+
+// Helper function to compute the consumed capacity as a percent of the
+// provisioned capacity. The average comes from the consumed sum over the
+// period, the peak from the busiest 5 minutes of the window.
+func getDynamoDBUtilization(ctx context.Context, cwClient *cloudwatch.Client, dimensions []types.Dimension, throughput *ddbTypes.ProvisionedThroughputDescription, metrics map[string]float64, period int32, timeParams map[string]time.Time) {
+	if throughput == nil {
+		return
+	}
+
+	capacities := []struct {
+		Prefix      string
+		MetricName  string
+		Provisioned int64
+	}{
+		{"Read", "ConsumedReadCapacityUnits", aws.ToInt64(throughput.ReadCapacityUnits)},
+		{"Write", "ConsumedWriteCapacityUnits", aws.ToInt64(throughput.WriteCapacityUnits)},
+	}
+
+	for _, capacity := range capacities {
+		if capacity.Provisioned <= 0 {
+			continue
+		}
+		provisioned := float64(capacity.Provisioned)
+		metrics[capacity.Prefix+"CapacityProvisioned"] = provisioned
+		metrics[capacity.Prefix+"Utilization_Average"] = capacityUtilization(metrics[capacity.MetricName], float64(period), provisioned)
+
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/DynamoDB"),
+			MetricName: aws.String(capacity.MetricName),
+			Dimensions: dimensions,
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     aws.Int32(300),
+			Statistics: []types.Statistic{types.StatisticSum},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			utils.Logger.Error("Failed to get DynamoDB peak consumed capacity",
+				zap.Error(err),
+				zap.String("metricName", capacity.MetricName),
+			)
+			continue
+		}
+
+		metrics[capacity.Prefix+"Utilization_Peak"] = capacityUtilization(peakSum(result.Datapoints), 300.0, provisioned)
+	}
+}
+
+// Helper function to convert the capacity units consumed over a number of
+// seconds into a percent of the provisioned units per second
+func capacityUtilization(consumed, seconds, provisioned float64) float64 {
+	if seconds <= 0 || provisioned <= 0 {
+		return 0
+	}
+	return consumed / seconds / provisioned * 100.0
+}
+
+// Helper function to get the largest Sum of the datapoints
+func peakSum(datapoints []types.Datapoint) float64 {
+	var peak float64
+	for _, datapoint := range datapoints {
+		if datapoint.Sum != nil && *datapoint.Sum > peak {
+			peak = *datapoint.Sum
+		}
+	}
+	return peak
 }
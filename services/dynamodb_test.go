@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+func TestCapacityUtilization(t *testing.T) {
+	tests := []struct {
+		name        string
+		consumed    float64
+		seconds     float64
+		provisioned float64
+		want        float64
+	}{
+		{"hourly average", 36000, 3600, 20, 50},
+		{"daily average", 86400, 86400, 4, 25},
+		{"peak over capacity", 9000, 300, 10, 300},
+		{"nothing consumed", 0, 3600, 5, 0},
+		{"no provisioned capacity", 100, 300, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capacityUtilization(tt.consumed, tt.seconds, tt.provisioned); got != tt.want {
+				t.Errorf("capacityUtilization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeakSum(t *testing.T) {
+	tests := []struct {
+		name       string
+		datapoints []types.Datapoint
+		want       float64
+	}{
+		{"no datapoints", nil, 0},
+		{"largest sum", []types.Datapoint{{Sum: aws.Float64(12)}, {Sum: aws.Float64(40)}, {Sum: aws.Float64(7)}}, 40},
+		{"datapoints without sum", []types.Datapoint{{}, {Sum: aws.Float64(3)}}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peakSum(tt.datapoints); got != tt.want {
+				t.Errorf("peakSum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
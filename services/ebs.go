@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// EBS storage list prices per GB-month in us-east-1. Provisioned IOPS and
+// throughput are not included, so io1/io2/gp3 estimates are a lower bound.
+var ebsPricePerGBMonth = map[ec2Types.VolumeType]float64{
+	ec2Types.VolumeTypeGp2:      0.10,
+	ec2Types.VolumeTypeGp3:      0.08,
+	ec2Types.VolumeTypeIo1:      0.125,
+	ec2Types.VolumeTypeIo2:      0.125,
+	ec2Types.VolumeTypeSt1:      0.045,
+	ec2Types.VolumeTypeSc1:      0.015,
+	ec2Types.VolumeTypeStandard: 0.05,
+}
+
+// GetAttachedVolumes returns the volumes attached to the given instances
+func GetAttachedVolumes(ctx context.Context, ec2Client *ec2.Client, instanceIDs []string) ([]*utils.EBSVolume, error) {
+	input := &ec2.DescribeVolumesInput{
+		Filters: []ec2Types.Filter{
+			{
+				Name:   aws.String("attachment.instance-id"),
+				Values: instanceIDs,
+			},
+		},
+	}
+
+	var volumes []*utils.EBSVolume
+	paginator := ec2.NewDescribeVolumesPaginator(ec2Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe attached volumes: %w", err)
+		}
+
+		for _, volume := range output.Volumes {
+			ebsVolume := newEBSVolume(volume)
+			if len(volume.Attachments) > 0 {
+				ebsVolume.InstanceID = aws.ToString(volume.Attachments[0].InstanceId)
+				ebsVolume.Device = aws.ToString(volume.Attachments[0].Device)
+			}
+			volumes = append(volumes, ebsVolume)
+		}
+	}
+
+	sort.Slice(volumes, func(i, j int) bool {
+		if volumes[i].InstanceID != volumes[j].InstanceID {
+			return volumes[i].InstanceID < volumes[j].InstanceID
+		}
+		return volumes[i].Device < volumes[j].Device
+	})
+
+	return volumes, nil
+}
+
+// GetUnattachedVolumes returns the available (unattached) volumes of the
+// region, largest monthly cost first
+func GetUnattachedVolumes(ctx context.Context, ec2Client *ec2.Client) ([]*utils.EBSVolume, error) {
+	input := &ec2.DescribeVolumesInput{
+		Filters: []ec2Types.Filter{
+			{
+				Name:   aws.String("status"),
+				Values: []string{string(ec2Types.VolumeStateAvailable)},
+			},
+		},
+	}
+
+	var volumes []*utils.EBSVolume
+	paginator := ec2.NewDescribeVolumesPaginator(ec2Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe unattached volumes: %w", err)
+		}
+
+		for _, volume := range output.Volumes {
+			volumes = append(volumes, newEBSVolume(volume))
+		}
+	}
+
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].MonthlyCost > volumes[j].MonthlyCost
+	})
+
+	return volumes, nil
+}
+
+// Helper function to build the report of a volume with its name tag and cost estimate
+func newEBSVolume(volume ec2Types.Volume) *utils.EBSVolume {
+	ebsVolume := &utils.EBSVolume{
+		VolumeID:   aws.ToString(volume.VolumeId),
+		VolumeType: string(volume.VolumeType),
+		SizeGB:     aws.ToInt32(volume.Size),
+		CreateTime: aws.ToTime(volume.CreateTime),
+		Metrics:    map[string]float64{},
+	}
+	ebsVolume.MonthlyCost = float64(ebsVolume.SizeGB) * ebsPricePerGBMonth[volume.VolumeType]
+
+	for _, tag := range volume.Tags {
+		if aws.ToString(tag.Key) == "Name" {
+			ebsVolume.Name = aws.ToString(tag.Value)
+		}
+	}
+
+	return ebsVolume
+}
+
+// EBSMetrics fills the I/O metrics of a volume. BurstBalance is only
+// published for the burstable gp2, st1 and sc1 volume types.
+func EBSMetrics(ctx context.Context, cwClient *cloudwatch.Client, volume *utils.EBSVolume, timeParams map[string]time.Time) error {
+	period := aws.Int32(3600)
+	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
+		period = aws.Int32(86400)
+	}
+
+	burstable := false
+	switch ec2Types.VolumeType(volume.VolumeType) {
+	case ec2Types.VolumeTypeGp2, ec2Types.VolumeTypeSt1, ec2Types.VolumeTypeSc1:
+		burstable = true
+	}
+
+	ebsMetrics := []struct {
+		Name      string
+		Statistic string
+		Unit      string
+	}{
+		{"VolumeReadOps", "Sum", "count"},
+		{"VolumeWriteOps", "Sum", "count"},
+		{"VolumeReadBytes", "Sum", "MB"},
+		{"VolumeWriteBytes", "Sum", "MB"},
+		{"VolumeQueueLength", "Average", "count"},
+		{"BurstBalance", "Minimum", "%"},
+	}
+
+	for _, metric := range ebsMetrics {
+		if metric.Name == "BurstBalance" && !burstable {
+			continue
+		}
+
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/EBS"),
+			MetricName: aws.String(metric.Name),
+			Dimensions: []types.Dimension{
+				{
+					Name:  aws.String("VolumeId"),
+					Value: aws.String(volume.VolumeID),
+				},
+			},
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			return fmt.Errorf("error getting %s: %v", metric.Name, err)
+		}
+
+		// gp2 volumes over 1 TiB never deplete credits and publish no BurstBalance
+		if len(result.Datapoints) == 0 {
+			if metric.Name != "BurstBalance" {
+				volume.Metrics[metric.Name] = 0.0
+			}
+			continue
+		}
+
+		var value float64
+		switch metric.Statistic {
+		case "Sum":
+			value = aws.ToFloat64(result.Datapoints[0].Sum)
+		case "Average":
+			value = aws.ToFloat64(result.Datapoints[0].Average)
+		case "Minimum":
+			value = aws.ToFloat64(result.Datapoints[0].Minimum)
+		}
+		if metric.Unit == "MB" {
+			value = value / (1024.0 * 1024.0)
+		}
+		volume.Metrics[metric.Name] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestNewEBSVolume(t *testing.T) {
+	tests := []struct {
+		name       string
+		volumeType ec2Types.VolumeType
+		tags       []ec2Types.Tag
+		wantName   string
+		wantCost   float64
+	}{
+		{"gp3", ec2Types.VolumeTypeGp3, nil, "", 8},
+		{
+			name:       "io2 with a Name tag",
+			volumeType: ec2Types.VolumeTypeIo2,
+			tags: []ec2Types.Tag{
+				{Key: aws.String("env"), Value: aws.String("prod")},
+				{Key: aws.String("Name"), Value: aws.String("data")},
+			},
+			wantName: "data",
+			wantCost: 12.5,
+		},
+		{"unknown type", ec2Types.VolumeType("gp4"), nil, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newEBSVolume(ec2Types.Volume{
+				VolumeId:   aws.String("vol-1"),
+				VolumeType: tt.volumeType,
+				Size:       aws.Int32(100),
+				Tags:       tt.tags,
+			})
+			if got.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", got.Name, tt.wantName)
+			}
+			if got.MonthlyCost != tt.wantCost {
+				t.Errorf("MonthlyCost = %v, want %v", got.MonthlyCost, tt.wantCost)
+			}
+		})
+	}
+}
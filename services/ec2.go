@@ -10,7 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
 )
 
-// Does NOT track disk read/write metrics (EBS volumes)
+// Disk read/write metrics of the attached volumes are reported by the EBS collector
 
 func EC2Metrics(ctx context.Context, cwClient *cloudwatch.Client, instanceID string, timeParams map[string]time.Time) (map[string]float64, error) {
 	metrics := map[string]float64{}
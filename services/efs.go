@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efsTypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+)
+
+// GetEFSFileSystems returns the given file systems, or every file system of
+// the region when discover is set. StorageBytes starts from the size reported
+// by DescribeFileSystems, which is refreshed about once an hour.
+func GetEFSFileSystems(ctx context.Context, efsClient *efs.Client, fileSystemIDs []string, discover bool) ([]*utils.EFSFileSystem, error) {
+	var fileSystems []efsTypes.FileSystemDescription
+	if discover {
+		paginator := efs.NewDescribeFileSystemsPaginator(efsClient, &efs.DescribeFileSystemsInput{})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe file systems: %w", err)
+			}
+			fileSystems = append(fileSystems, output.FileSystems...)
+		}
+	} else {
+		for _, id := range fileSystemIDs {
+			output, err := efsClient.DescribeFileSystems(ctx, &efs.DescribeFileSystemsInput{
+				FileSystemId: aws.String(id),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe file system %s: %w", id, err)
+			}
+			fileSystems = append(fileSystems, output.FileSystems...)
+		}
+	}
+
+	var reports []*utils.EFSFileSystem
+	for _, fileSystem := range fileSystems {
+		report := &utils.EFSFileSystem{
+			FileSystemID:    aws.ToString(fileSystem.FileSystemId),
+			Name:            aws.ToString(fileSystem.Name),
+			PerformanceMode: string(fileSystem.PerformanceMode),
+			ThroughputMode:  string(fileSystem.ThroughputMode),
+			Metrics:         map[string]float64{},
+		}
+		if fileSystem.SizeInBytes != nil {
+			report.Metrics["StorageBytes"] = float64(fileSystem.SizeInBytes.Value) / (1024.0 * 1024.0 * 1024.0)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// EFSMetrics fills the metrics of a file system. PercentIOLimit is only
+// published in General Purpose performance mode and BurstCreditBalance is
+// only relevant in Bursting throughput mode. ThroughputUtilization is the
+// peak per-minute metered throughput as a percentage of PermittedThroughput.
+func EFSMetrics(ctx context.Context, cwClient *cloudwatch.Client, fileSystem *utils.EFSFileSystem, timeParams map[string]time.Time) error {
+	period := aws.Int32(3600)
+	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
+		period = aws.Int32(86400)
+	}
+
+	efsMetrics := []struct {
+		Name      string
+		Statistic string
+		Unit      string
+	}{
+		{"PercentIOLimit", "Maximum", "%"},
+		{"BurstCreditBalance", "Minimum", "GB"},
+		{"ClientConnections", "Maximum", "count"},
+		{"StorageBytes", "Average", "GB"},
+	}
+
+	for _, metric := range efsMetrics {
+		if metric.Name == "PercentIOLimit" && fileSystem.PerformanceMode != string(efsTypes.PerformanceModeGeneralPurpose) {
+			continue
+		}
+		if metric.Name == "BurstCreditBalance" && fileSystem.ThroughputMode != string(efsTypes.ThroughputModeBursting) {
+			continue
+		}
+
+		dimensions := []types.Dimension{
+			{
+				Name:  aws.String("FileSystemId"),
+				Value: aws.String(fileSystem.FileSystemID),
+			},
+		}
+		if metric.Name == "StorageBytes" {
+			dimensions = append(dimensions, types.Dimension{
+				Name:  aws.String("StorageClass"),
+				Value: aws.String("Total"),
+			})
+		}
+
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/EFS"),
+			MetricName: aws.String(metric.Name),
+			Dimensions: dimensions,
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			return fmt.Errorf("error getting %s: %v", metric.Name, err)
+		}
+
+		if len(result.Datapoints) == 0 {
+			// Keep the size from DescribeFileSystems
+			if _, ok := fileSystem.Metrics[metric.Name]; !ok {
+				fileSystem.Metrics[metric.Name] = 0.0
+			}
+			continue
+		}
+
+		var value float64
+		switch metric.Statistic {
+		case "Sum":
+			value = aws.ToFloat64(result.Datapoints[0].Sum)
+		case "Average":
+			value = aws.ToFloat64(result.Datapoints[0].Average)
+		case "Minimum":
+			value = aws.ToFloat64(result.Datapoints[0].Minimum)
+		case "Maximum":
+			value = aws.ToFloat64(result.Datapoints[0].Maximum)
+		}
+		if metric.Unit == "GB" {
+			value = value / (1024.0 * 1024.0 * 1024.0)
+		}
+		fileSystem.Metrics[metric.Name] = value
+	}
+
+	utilization, err := efsPeakThroughputUtilization(ctx, cwClient, fileSystem.FileSystemID, timeParams)
+	if err != nil {
+		return err
+	}
+	fileSystem.Metrics["ThroughputUtilization"] = utilization
+
+	return nil
+}
+
+// Helper function to get the busiest minute of the window as a percentage of
+// the permitted throughput, as the EFS console computes it. Hourly or daily
+// sums would average the peaks away.
+func efsPeakThroughputUtilization(ctx context.Context, cwClient *cloudwatch.Client, fileSystemID string, timeParams map[string]time.Time) (float64, error) {
+	metered, err := getEFSMinuteSeries(ctx, cwClient, fileSystemID, "MeteredIOBytes", types.StatisticSum, timeParams)
+	if err != nil {
+		return 0, err
+	}
+	permitted, err := getEFSMinuteSeries(ctx, cwClient, fileSystemID, "PermittedThroughput", types.StatisticAverage, timeParams)
+	if err != nil {
+		return 0, err
+	}
+
+	return efsPeakUtilization(metered, permitted), nil
+}
+
+// Helper function to get the highest per-minute metered throughput as a
+// percentage of the permitted throughput of the same minute. Minutes without
+// a permitted throughput are skipped.
+func efsPeakUtilization(metered, permitted map[time.Time]float64) float64 {
+	var peak float64
+	for timestamp, bytes := range metered {
+		if permitted[timestamp] > 0 {
+			peak = max(peak, bytes/60/permitted[timestamp]*100)
+		}
+	}
+	return peak
+}
+
+// Helper function to get a metric of a file system in 60s periods, keyed by
+// timestamp. A query returns at most 1440 datapoints, so it is read by day.
+func getEFSMinuteSeries(ctx context.Context, cwClient *cloudwatch.Client, fileSystemID, metricName string, statistic types.Statistic, timeParams map[string]time.Time) (map[time.Time]float64, error) {
+	series := make(map[time.Time]float64)
+	for start := timeParams["startTime"]; start.Before(timeParams["endTime"]); start = start.Add(24 * time.Hour) {
+		end := start.Add(24 * time.Hour)
+		if end.After(timeParams["endTime"]) {
+			end = timeParams["endTime"]
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/EFS"),
+			MetricName: aws.String(metricName),
+			Dimensions: []types.Dimension{
+				{
+					Name:  aws.String("FileSystemId"),
+					Value: aws.String(fileSystemID),
+				},
+			},
+			StartTime:  aws.Time(start),
+			EndTime:    aws.Time(end),
+			Period:     aws.Int32(60),
+			Statistics: []types.Statistic{statistic},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s per minute: %v", metricName, err)
+		}
+
+		for _, datapoint := range result.Datapoints {
+			value := aws.ToFloat64(datapoint.Average)
+			if statistic == types.StatisticSum {
+				value = aws.ToFloat64(datapoint.Sum)
+			}
+			series[aws.ToTime(datapoint.Timestamp)] = value
+		}
+	}
+	return series, nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEFSPeakUtilization(t *testing.T) {
+	minute := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	next := minute.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		metered   map[time.Time]float64
+		permitted map[time.Time]float64
+		want      float64
+	}{
+		{
+			name: "no datapoints",
+		},
+		{
+			// 60 MB over a minute is 1 MB/s of the 4 MB/s permitted
+			name:      "single minute",
+			metered:   map[time.Time]float64{minute: 60e6},
+			permitted: map[time.Time]float64{minute: 4e6},
+			want:      25,
+		},
+		{
+			name:      "busiest minute relative to its permitted throughput",
+			metered:   map[time.Time]float64{minute: 120e6, next: 90e6},
+			permitted: map[time.Time]float64{minute: 4e6, next: 1e6},
+			want:      150,
+		},
+		{
+			name:      "minutes without permitted throughput skipped",
+			metered:   map[time.Time]float64{minute: 60e6, next: 600e6},
+			permitted: map[time.Time]float64{minute: 2e6, next: 0},
+			want:      50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := efsPeakUtilization(tt.metered, tt.permitted); got != tt.want {
+				t.Errorf("efsPeakUtilization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"telegraws/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/health"
+	healthTypes "github.com/aws/aws-sdk-go-v2/service/health/types"
+	"go.uber.org/zap"
+)
+
+// DescribeAffectedEntities accepts at most 10 event ARNs per request
+const healthEntitiesBatchSize = 10
+
+// HealthEvents returns the open and upcoming AWS Health events of the account
+// in the region (plus global events) with their affected resources. Public
+// events are skipped unless includePublic. The AWS Health API requires a
+// Business, Enterprise On-Ramp or Enterprise support plan and is only served
+// from us-east-1.
+func HealthEvents(ctx context.Context, healthClient *health.Client, region string, maxEntities int, includePublic bool) ([]utils.HealthEvent, error) {
+	input := &health.DescribeEventsInput{
+		Filter: &healthTypes.EventFilter{
+			EventStatusCodes: []healthTypes.EventStatusCode{
+				healthTypes.EventStatusCodeOpen,
+				healthTypes.EventStatusCodeUpcoming,
+			},
+			Regions: []string{region, "global"},
+		},
+	}
+
+	var events []utils.HealthEvent
+	paginator := health.NewDescribeEventsPaginator(healthClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe health events: %w", err)
+		}
+
+		for _, event := range output.Events {
+			if !reportHealthEvent(event, includePublic) {
+				continue
+			}
+			events = append(events, utils.HealthEvent{
+				Arn:       aws.ToString(event.Arn),
+				Service:   aws.ToString(event.Service),
+				TypeCode:  aws.ToString(event.EventTypeCode),
+				Category:  string(event.EventTypeCategory),
+				Region:    aws.ToString(event.Region),
+				Status:    string(event.StatusCode),
+				StartTime: aws.ToTime(event.StartTime),
+				EndTime:   aws.ToTime(event.EndTime),
+			})
+		}
+	}
+
+	for start := 0; start < len(events); start += healthEntitiesBatchSize {
+		end := min(start+healthEntitiesBatchSize, len(events))
+		if err := getHealthEntities(ctx, healthClient, events[start:end], maxEntities); err != nil {
+			utils.Logger.Error("Failed to get health event affected entities", zap.Error(err))
+		}
+	}
+
+	sortHealthEvents(events)
+
+	return events, nil
+}
+
+// Helper function to check that an event concerns the account. Public events
+// are published for every account using the service in the region, whether
+// or not it has affected resources.
+func reportHealthEvent(event healthTypes.Event, includePublic bool) bool {
+	return includePublic || event.EventScopeCode != healthTypes.EventScopeCodePublic
+}
+
+// Helper function to sort the events with issues first, then scheduled
+// changes and notifications, soonest first
+func sortHealthEvents(events []utils.HealthEvent) {
+	categoryOrder := map[string]int{
+		string(healthTypes.EventTypeCategoryIssue):               0,
+		string(healthTypes.EventTypeCategoryScheduledChange):     1,
+		string(healthTypes.EventTypeCategoryAccountNotification): 2,
+		string(healthTypes.EventTypeCategoryInvestigation):       3,
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if categoryOrder[events[i].Category] != categoryOrder[events[j].Category] {
+			return categoryOrder[events[i].Category] < categoryOrder[events[j].Category]
+		}
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+}
+
+// Helper function to fill the affected entities of a batch of events,
+// keeping at most maxEntities per event and counting the rest
+func getHealthEntities(ctx context.Context, healthClient *health.Client, events []utils.HealthEvent, maxEntities int) error {
+	byArn := make(map[string]*utils.HealthEvent, len(events))
+	var eventArns []string
+	for i := range events {
+		byArn[events[i].Arn] = &events[i]
+		eventArns = append(eventArns, events[i].Arn)
+	}
+
+	input := &health.DescribeAffectedEntitiesInput{
+		Filter: &healthTypes.EntityFilter{
+			EventArns: eventArns,
+		},
+	}
+
+	paginator := health.NewDescribeAffectedEntitiesPaginator(healthClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe affected entities: %w", err)
+		}
+
+		for _, entity := range output.Entities {
+			event, ok := byArn[aws.ToString(entity.EventArn)]
+			if !ok {
+				continue
+			}
+
+			// Account-wide events report the account ID as the entity
+			value := aws.ToString(entity.EntityValue)
+			if value == "" || value == aws.ToString(entity.AwsAccountId) {
+				continue
+			}
+
+			event.EntityCount++
+			if len(event.Entities) < maxEntities {
+				event.Entities = append(event.Entities, value)
+			}
+		}
+	}
+
+	return nil
+}
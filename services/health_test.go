@@ -0,0 +1,60 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+	"time"
+
+	healthTypes "github.com/aws/aws-sdk-go-v2/service/health/types"
+)
+
+func TestReportHealthEvent(t *testing.T) {
+	tests := []struct {
+		name          string
+		scope         healthTypes.EventScopeCode
+		includePublic bool
+		want          bool
+	}{
+		{"account specific", healthTypes.EventScopeCodeAccountSpecific, false, true},
+		{"public", healthTypes.EventScopeCodePublic, false, false},
+		{"public included", healthTypes.EventScopeCodePublic, true, true},
+		{"no scope", healthTypes.EventScopeCodeNone, false, true},
+		{"scope not returned", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := healthTypes.Event{EventScopeCode: tt.scope}
+			if got := reportHealthEvent(event, tt.includePublic); got != tt.want {
+				t.Errorf("reportHealthEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortHealthEvents(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	event := func(arn, category string, days int) utils.HealthEvent {
+		return utils.HealthEvent{Arn: arn, Category: category, StartTime: day.AddDate(0, 0, days)}
+	}
+
+	events := []utils.HealthEvent{
+		event("notification", "accountNotification", 0),
+		event("retirement", "scheduledChange", 5),
+		event("maintenance", "scheduledChange", 2),
+		event("later issue", "issue", 1),
+		event("issue", "issue", 0),
+	}
+	want := []string{"issue", "later issue", "maintenance", "retirement", "notification"}
+
+	sortHealthEvents(events)
+
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Arn)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortHealthEvents() = %v, want %v", got, want)
+	}
+}
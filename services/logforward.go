@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// LogForwarder filters CloudWatch Logs subscription batches and rate limits
+// the alerts per log group. The state lives in memory, so it is only shared
+// by the invocations of the same warm Lambda container.
+type LogForwarder struct {
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+}
+
+func NewLogForwarder() *LogForwarder {
+	return &LogForwarder{
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// Process decodes a subscription event (gzip and base64 encoded) and returns
+// the alert to send, or nil when no line matches or the log group was alerted
+// less than rateLimit ago. Lines held back are reported by the next alert.
+// The rate limit only starts once the alert is passed to MarkSent, so an
+// alert that fails to send is built again when the event is retried.
+func (f *LogForwarder) Process(event events.CloudwatchLogsEvent, patterns []string, rateLimit time.Duration, maxLines int) (*utils.LogAlert, error) {
+	data, err := event.AWSLogs.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CloudWatch Logs event: %w", err)
+	}
+
+	// CloudWatch Logs sends a control message when the subscription is created
+	if data.MessageType != "DATA_MESSAGE" {
+		return nil, nil
+	}
+
+	var regexps []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forwarding pattern %q: %w", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+
+	alert := &utils.LogAlert{LogGroup: data.LogGroup}
+	for _, logEvent := range data.LogEvents {
+		if len(regexps) > 0 && !matchesAnyRegexp(logEvent.Message, regexps) {
+			continue
+		}
+
+		timestamp := time.UnixMilli(logEvent.Timestamp).UTC()
+		if alert.Matched == 0 || timestamp.Before(alert.FirstSeen) {
+			alert.FirstSeen = timestamp
+		}
+		if timestamp.After(alert.LastSeen) {
+			alert.LastSeen = timestamp
+		}
+
+		alert.Matched++
+		if len(alert.Lines) < maxLines {
+			alert.Lines = append(alert.Lines, logEvent.Message)
+		}
+	}
+
+	if alert.Matched == 0 {
+		return nil, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := f.lastSent[data.LogGroup]; ok && now.Sub(last) < rateLimit {
+		f.suppressed[data.LogGroup] += alert.Matched
+		return nil, nil
+	}
+
+	alert.Suppressed = f.suppressed[data.LogGroup]
+
+	return alert, nil
+}
+
+// Flush returns a summary alert for every log group with lines held back by
+// the rate limit whose window is over, so the tail of a burst is reported even
+// when no later event arrives. A rateLimit of 0 flushes every log group. The
+// lines stay held back until the summary is passed to MarkSent.
+func (f *LogForwarder) Flush(rateLimit time.Duration) []*utils.LogAlert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	var alerts []*utils.LogAlert
+	for logGroup, suppressed := range f.suppressed {
+		if suppressed == 0 || now.Sub(f.lastSent[logGroup]) < rateLimit {
+			continue
+		}
+		alerts = append(alerts, &utils.LogAlert{
+			LogGroup:   logGroup,
+			Suppressed: suppressed,
+		})
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].LogGroup < alerts[j].LogGroup
+	})
+
+	return alerts
+}
+
+// MarkSent starts the rate limit window of the alert's log group and clears
+// the held back lines the alert reported
+func (f *LogForwarder) MarkSent(alert *utils.LogAlert, sentAt time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.suppressed[alert.LogGroup] -= alert.Suppressed
+	if f.suppressed[alert.LogGroup] < 0 {
+		f.suppressed[alert.LogGroup] = 0
+	}
+	f.lastSent[alert.LogGroup] = sentAt
+}
+
+func matchesAnyRegexp(message string, regexps []*regexp.Regexp) bool {
+	for _, re := range regexps {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"telegraws/utils"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+var logForwardBase = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// logForwardEvent encodes a subscription batch the way CloudWatch Logs sends
+// it. Message i is timestamped i seconds after logForwardBase.
+func logForwardEvent(t *testing.T, messageType, logGroup string, messages ...string) events.CloudwatchLogsEvent {
+	t.Helper()
+	data := events.CloudwatchLogsData{
+		MessageType: messageType,
+		LogGroup:    logGroup,
+	}
+	for i, message := range messages {
+		data.LogEvents = append(data.LogEvents, events.CloudwatchLogsLogEvent{
+			Timestamp: logForwardBase.Add(time.Duration(i) * time.Second).UnixMilli(),
+			Message:   message,
+		})
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(payload)
+	writer.Close()
+
+	return events.CloudwatchLogsEvent{
+		AWSLogs: events.CloudwatchLogsRawData{Data: base64.StdEncoding.EncodeToString(compressed.Bytes())},
+	}
+}
+
+func TestLogForwarderProcess(t *testing.T) {
+	messages := []string{"INFO started", "ERROR timeout", "WARN slow", "ERROR refused", "FATAL crash"}
+
+	tests := []struct {
+		name        string
+		messageType string
+		patterns    []string
+		maxLines    int
+		want        *utils.LogAlert
+		wantErr     bool
+	}{
+		{
+			name:        "every line without patterns",
+			messageType: "DATA_MESSAGE",
+			maxLines:    10,
+			want: &utils.LogAlert{
+				LogGroup:  "/aws/lambda/orders",
+				Matched:   5,
+				Lines:     messages,
+				FirstSeen: logForwardBase,
+				LastSeen:  logForwardBase.Add(4 * time.Second),
+			},
+		},
+		{
+			name:        "matching lines capped by maxLines",
+			messageType: "DATA_MESSAGE",
+			patterns:    []string{"^ERROR", "^FATAL"},
+			maxLines:    2,
+			want: &utils.LogAlert{
+				LogGroup:  "/aws/lambda/orders",
+				Matched:   3,
+				Lines:     []string{"ERROR timeout", "ERROR refused"},
+				FirstSeen: logForwardBase.Add(1 * time.Second),
+				LastSeen:  logForwardBase.Add(4 * time.Second),
+			},
+		},
+		{
+			name:        "no matching line",
+			messageType: "DATA_MESSAGE",
+			patterns:    []string{"^PANIC"},
+			maxLines:    10,
+		},
+		{
+			name:        "control message",
+			messageType: "CONTROL_MESSAGE",
+			maxLines:    10,
+		},
+		{
+			name:        "invalid pattern",
+			messageType: "DATA_MESSAGE",
+			patterns:    []string{"("},
+			maxLines:    10,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := logForwardEvent(t, tt.messageType, "/aws/lambda/orders", messages...)
+			got, err := NewLogForwarder().Process(event, tt.patterns, time.Hour, tt.maxLines)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Process() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogForwarderProcessInvalidData(t *testing.T) {
+	event := events.CloudwatchLogsEvent{AWSLogs: events.CloudwatchLogsRawData{Data: "not base64"}}
+	if _, err := NewLogForwarder().Process(event, nil, time.Hour, 10); err == nil {
+		t.Error("Process() error = nil, want a decoding error")
+	}
+}
+
+func TestLogForwarderRateLimit(t *testing.T) {
+	// Alerts are marked as sent unless sendFails
+	type batch struct {
+		logGroup       string
+		lines          int
+		rateLimit      time.Duration
+		sendFails      bool
+		wantSent       bool
+		wantSuppressed int
+	}
+
+	tests := []struct {
+		name    string
+		batches []batch
+	}{
+		{
+			name: "later batches are held back",
+			batches: []batch{
+				{"/aws/lambda/orders", 2, time.Hour, false, true, 0},
+				{"/aws/lambda/orders", 3, time.Hour, false, false, 0},
+				{"/aws/lambda/orders", 1, time.Hour, false, false, 0},
+			},
+		},
+		{
+			name: "log groups are limited separately",
+			batches: []batch{
+				{"/aws/lambda/orders", 2, time.Hour, false, true, 0},
+				{"/aws/lambda/payments", 1, time.Hour, false, true, 0},
+				{"/aws/lambda/orders", 1, time.Hour, false, false, 0},
+			},
+		},
+		{
+			// A zero rate limit ends the window of the previous alert
+			name: "held back lines reported by the next alert",
+			batches: []batch{
+				{"/aws/lambda/orders", 2, time.Hour, false, true, 0},
+				{"/aws/lambda/orders", 3, time.Hour, false, false, 0},
+				{"/aws/lambda/orders", 1, time.Hour, false, false, 0},
+				{"/aws/lambda/orders", 1, 0, false, true, 4},
+				{"/aws/lambda/orders", 1, 0, false, true, 0},
+			},
+		},
+		{
+			name: "failed alerts don't start the window",
+			batches: []batch{
+				{"/aws/lambda/orders", 2, time.Hour, true, true, 0},
+				{"/aws/lambda/orders", 1, time.Hour, false, true, 0},
+				{"/aws/lambda/orders", 3, time.Hour, false, false, 0},
+				{"/aws/lambda/orders", 1, 0, true, true, 3},
+				{"/aws/lambda/orders", 1, 0, false, true, 3},
+				{"/aws/lambda/orders", 1, 0, false, true, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarder := NewLogForwarder()
+			for i, b := range tt.batches {
+				messages := make([]string, b.lines)
+				for j := range messages {
+					messages[j] = "ERROR timeout"
+				}
+				event := logForwardEvent(t, "DATA_MESSAGE", b.logGroup, messages...)
+
+				alert, err := forwarder.Process(event, nil, b.rateLimit, 10)
+				if err != nil {
+					t.Fatalf("batch %d: Process() error = %v", i, err)
+				}
+				if (alert != nil) != b.wantSent {
+					t.Fatalf("batch %d: Process() sent = %v, want %v", i, alert != nil, b.wantSent)
+				}
+				if alert == nil {
+					continue
+				}
+				if alert.Suppressed != b.wantSuppressed {
+					t.Errorf("batch %d: Suppressed = %d, want %d", i, alert.Suppressed, b.wantSuppressed)
+				}
+				if !b.sendFails {
+					forwarder.MarkSent(alert, time.Now())
+				}
+			}
+		})
+	}
+}
+
+func TestLogForwarderFlush(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit time.Duration
+		want      []*utils.LogAlert
+	}{
+		{
+			name:      "window not over",
+			rateLimit: time.Hour,
+		},
+		{
+			name:      "window over",
+			rateLimit: 0,
+			want: []*utils.LogAlert{
+				{LogGroup: "/aws/lambda/orders", Suppressed: 2},
+				{LogGroup: "/aws/lambda/payments", Suppressed: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarder := NewLogForwarder()
+			for _, event := range []events.CloudwatchLogsEvent{
+				logForwardEvent(t, "DATA_MESSAGE", "/aws/lambda/payments", "ERROR a"),
+				logForwardEvent(t, "DATA_MESSAGE", "/aws/lambda/payments", "ERROR b"),
+				logForwardEvent(t, "DATA_MESSAGE", "/aws/lambda/orders", "ERROR c"),
+				logForwardEvent(t, "DATA_MESSAGE", "/aws/lambda/orders", "ERROR d", "ERROR e"),
+				logForwardEvent(t, "DATA_MESSAGE", "/aws/lambda/search", "ERROR f"),
+			} {
+				if alert, _ := forwarder.Process(event, nil, time.Hour, 10); alert != nil {
+					forwarder.MarkSent(alert, time.Now())
+				}
+			}
+
+			got := forwarder.Flush(tt.rateLimit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flush() = %+v, want %+v", got, tt.want)
+			}
+
+			// Summaries that fail to send are flushed again
+			if again := forwarder.Flush(tt.rateLimit); !reflect.DeepEqual(again, tt.want) {
+				t.Errorf("Flush() before MarkSent = %+v, want %+v", again, tt.want)
+			}
+
+			// Sent summaries are only reported once
+			if tt.want != nil {
+				for _, alert := range got {
+					forwarder.MarkSent(alert, time.Now())
+				}
+				if again := forwarder.Flush(0); len(again) != 0 {
+					t.Errorf("Flush() after MarkSent = %+v, want none", again)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	sqTypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+)
+
+// QuotaUsage compares the applied value of a quota with its usage metric in
+// the AWS/Usage namespace, using the peak over the report window. Quotas that
+// were never increased have no applied value and use the AWS default. Rate
+// quotas (Sum metrics such as CallCount) use the busiest minute scaled to the
+// period of the quota.
+func QuotaUsage(ctx context.Context, sqClient *servicequotas.Client, cwClient *cloudwatch.Client, serviceCode, quotaCode string, timeParams map[string]time.Time) (*utils.QuotaUsage, error) {
+	var quota *sqTypes.ServiceQuota
+	output, err := sqClient.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	var notFound *sqTypes.NoSuchResourceException
+	switch {
+	case err == nil:
+		quota = output.Quota
+	case errors.As(err, &notFound):
+		defaultOutput, err := sqClient.GetAWSDefaultServiceQuota(ctx, &servicequotas.GetAWSDefaultServiceQuotaInput{
+			ServiceCode: aws.String(serviceCode),
+			QuotaCode:   aws.String(quotaCode),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default quota %s/%s: %w", serviceCode, quotaCode, err)
+		}
+		quota = defaultOutput.Quota
+	default:
+		return nil, fmt.Errorf("failed to get quota %s/%s: %w", serviceCode, quotaCode, err)
+	}
+
+	usage := &utils.QuotaUsage{
+		ServiceCode: serviceCode,
+		QuotaCode:   quotaCode,
+		QuotaName:   aws.ToString(quota.QuotaName),
+		Value:       aws.ToFloat64(quota.Value),
+	}
+
+	metric := quota.UsageMetric
+	if metric == nil || metric.MetricName == nil {
+		return nil, fmt.Errorf("quota %s/%s has no usage metric", serviceCode, quotaCode)
+	}
+
+	var dimensions []types.Dimension
+	for name, value := range metric.MetricDimensions {
+		dimensions = append(dimensions, types.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(value),
+		})
+	}
+	sort.Slice(dimensions, func(i, j int) bool {
+		return aws.ToString(dimensions[i].Name) < aws.ToString(dimensions[j].Name)
+	})
+
+	statistic := types.StatisticMaximum
+	if aws.ToString(metric.MetricStatisticRecommendation) != "" {
+		statistic = types.Statistic(*metric.MetricStatisticRecommendation)
+	}
+
+	period := int32(3600)
+	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
+		period = 86400
+	}
+	switch statistic {
+	case types.StatisticMaximum, types.StatisticAverage:
+	case types.StatisticSum:
+		// Sum metrics count calls per minute for rate quotas
+		period = 60
+	default:
+		return nil, fmt.Errorf("quota %s/%s has unsupported usage statistic %s", serviceCode, quotaCode, statistic)
+	}
+
+	// A query returns at most 1440 datapoints, 60s periods are read by day
+	chunk := time.Duration(period) * 1440 * time.Second
+	for start := timeParams["startTime"]; start.Before(timeParams["endTime"]); start = start.Add(chunk) {
+		end := start.Add(chunk)
+		if end.After(timeParams["endTime"]) {
+			end = timeParams["endTime"]
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  metric.MetricNamespace,
+			MetricName: metric.MetricName,
+			Dimensions: dimensions,
+			StartTime:  aws.Time(start),
+			EndTime:    aws.Time(end),
+			Period:     aws.Int32(period),
+			Statistics: []types.Statistic{statistic},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting usage of %s/%s: %v", serviceCode, quotaCode, err)
+		}
+
+		for _, datapoint := range result.Datapoints {
+			var value float64
+			switch statistic {
+			case types.StatisticSum:
+				value = aws.ToFloat64(datapoint.Sum)
+			case types.StatisticAverage:
+				value = aws.ToFloat64(datapoint.Average)
+			default:
+				value = aws.ToFloat64(datapoint.Maximum)
+			}
+			usage.Usage = max(usage.Usage, value)
+		}
+	}
+
+	// Scale the busiest minute to the period of the quota, e.g. calls per second
+	if statistic == types.StatisticSum {
+		usage.Usage = usage.Usage / 60 * quotaPeriodSeconds(quota.Period)
+	}
+
+	if usage.Value > 0 {
+		usage.UsedPercent = usage.Usage / usage.Value * 100
+	}
+
+	return usage, nil
+}
+
+// Helper function to get the length of the period of a rate quota in seconds.
+// Rate quotas without a period are per second.
+func quotaPeriodSeconds(period *sqTypes.QuotaPeriod) float64 {
+	if period == nil {
+		return 1
+	}
+
+	var unit float64
+	switch period.PeriodUnit {
+	case sqTypes.PeriodUnitMicrosecond:
+		unit = 1e-6
+	case sqTypes.PeriodUnitMillisecond:
+		unit = 1e-3
+	case sqTypes.PeriodUnitMinute:
+		unit = 60
+	case sqTypes.PeriodUnitHour:
+		unit = 3600
+	case sqTypes.PeriodUnitDay:
+		unit = 86400
+	case sqTypes.PeriodUnitWeek:
+		unit = 7 * 86400
+	default:
+		unit = 1
+	}
+
+	value := aws.ToInt32(period.PeriodValue)
+	if value <= 0 {
+		value = 1
+	}
+	return unit * float64(value)
+}
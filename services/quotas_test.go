@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sqTypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+)
+
+func TestQuotaPeriodSeconds(t *testing.T) {
+	tests := []struct {
+		name   string
+		period *sqTypes.QuotaPeriod
+		want   float64
+	}{
+		{"no period", nil, 1},
+		{"per second", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitSecond, PeriodValue: aws.Int32(1)}, 1},
+		{"per 10 seconds", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitSecond, PeriodValue: aws.Int32(10)}, 10},
+		{"per minute", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitMinute, PeriodValue: aws.Int32(1)}, 60},
+		{"per 5 minutes", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitMinute, PeriodValue: aws.Int32(5)}, 300},
+		{"per hour", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitHour, PeriodValue: aws.Int32(1)}, 3600},
+		{"per day", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitDay, PeriodValue: aws.Int32(1)}, 86400},
+		{"per week", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitWeek, PeriodValue: aws.Int32(1)}, 604800},
+		{"per 100 milliseconds", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitMillisecond, PeriodValue: aws.Int32(100)}, 0.1},
+		{"missing value", &sqTypes.QuotaPeriod{PeriodUnit: sqTypes.PeriodUnitMinute}, 60},
+		{"unknown unit", &sqTypes.QuotaPeriod{PeriodUnit: "FORTNIGHT", PeriodValue: aws.Int32(2)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quotaPeriodSeconds(tt.period); got != tt.want {
+				t.Errorf("quotaPeriodSeconds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"telegraws/utils"
 	"time"
@@ -10,11 +11,57 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/pi"
+	piTypes "github.com/aws/aws-sdk-go-v2/service/pi/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"go.uber.org/zap"
 )
 
-func RDSMetrics(ctx context.Context, cwClient *cloudwatch.Client, clusterID string, instanceID string, timeParams map[string]time.Time) (map[string]float64, error) {
-	metrics := map[string]float64{}
+// Number of SQL statements and wait events listed per instance
+const rdsTopItems = 5
+
+// Helper function to discover the members of an Aurora cluster
+func getRDSClusterMembers(ctx context.Context, rdsClient *rds.Client, clusterID string) ([]utils.RDSInstance, error) {
+	clusterOutput, err := rdsClient.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(clusterID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe DB cluster: %w", err)
+	}
+
+	if len(clusterOutput.DBClusters) == 0 {
+		return nil, fmt.Errorf("DB cluster %s not found", clusterID)
+	}
+
+	return rdsClusterMembers(clusterOutput.DBClusters[0].DBClusterMembers), nil
+}
+
+// Helper function to turn the members of a cluster into instances, writer
+// first so the report always starts with the primary
+func rdsClusterMembers(members []rdsTypes.DBClusterMember) []utils.RDSInstance {
+	var writers, readers []utils.RDSInstance
+	for _, member := range members {
+		if member.DBInstanceIdentifier == nil {
+			continue
+		}
+
+		instance := utils.RDSInstance{
+			InstanceID: *member.DBInstanceIdentifier,
+			Role:       "reader",
+		}
+		if aws.ToBool(member.IsClusterWriter) {
+			instance.Role = "writer"
+			writers = append(writers, instance)
+		} else {
+			readers = append(readers, instance)
+		}
+	}
+
+	return append(writers, readers...)
+}
+
+func RDSMetrics(ctx context.Context, cwClient *cloudwatch.Client, rdsClient *rds.Client, piClient *pi.Client, clusterID string, instanceID string, timeParams map[string]time.Time, isDailyReport bool) (*utils.RDSReport, error) {
 	period := aws.Int32(3600)
 	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
 		period = aws.Int32(86400)
@@ -24,147 +71,366 @@ func RDSMetrics(ctx context.Context, cwClient *cloudwatch.Client, clusterID stri
 		return nil, fmt.Errorf("both clusterID and instanceID are empty - at least one is required")
 	}
 
+	report := &utils.RDSReport{ClusterID: clusterID}
+
+	if clusterID != "" {
+		members, err := getRDSClusterMembers(ctx, rdsClient, clusterID)
+		if err != nil {
+			// Fall back to the configured instance if topology discovery fails
+			utils.Logger.Error("Failed to discover Aurora cluster members",
+				zap.Error(err),
+				zap.String("clusterID", clusterID),
+			)
+		} else {
+			report.Instances = members
+		}
+	}
+
+	if len(report.Instances) == 0 && instanceID != "" {
+		report.Instances = []utils.RDSInstance{{InstanceID: instanceID}}
+	}
+
+	if err := describeRDSInstances(ctx, rdsClient, clusterID, report.Instances); err != nil {
+		utils.Logger.Error("Failed to get RDS instance classes", zap.Error(err))
+	}
+
 	// Instance-level metrics (per database instance)
-	if instanceID != "" {
-
-		instanceMetrics := []struct {
-			Name      string
-			Statistic string
-			Unit      string
-		}{
-			{"CPUUtilization", "Average", "%"},
-			{"CPUUtilization", "Maximum", "%"},
-			{"FreeableMemory", "Average", "bytes"},
-			{"DatabaseConnections", "Maximum", "count"},
-			{"ReadLatency", "Average", "seconds"},
-			{"WriteLatency", "Average", "seconds"},
-		}
-
-		for _, metric := range instanceMetrics {
-			input := &cloudwatch.GetMetricStatisticsInput{
-				Namespace:  aws.String("AWS/RDS"),
-				MetricName: aws.String(metric.Name),
-				Dimensions: []types.Dimension{
-					{
-						Name:  aws.String("DBInstanceIdentifier"),
-						Value: aws.String(instanceID),
-					},
-				},
-				StartTime:  aws.Time(timeParams["startTime"]),
-				EndTime:    aws.Time(timeParams["endTime"]),
-				Period:     period,
-				Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
-			}
+	for i := range report.Instances {
+		report.Instances[i].Metrics = rdsInstanceMetrics(ctx, cwClient, report.Instances[i], period, timeParams)
+	}
+
+	// Cluster-level metrics (for the entire Aurora cluster)
+	if clusterID != "" {
+		report.Cluster = rdsClusterMetrics(ctx, cwClient, clusterID, period, timeParams)
+	}
+
+	// Events and Performance Insights (daily only)
+	if isDailyReport {
+		events, err := getRDSEvents(ctx, rdsClient, report, timeParams)
+		if err != nil {
+			utils.Logger.Error("Failed to get RDS events", zap.Error(err))
+		} else {
+			report.Events = events
+		}
+
+		for i := range report.Instances {
+			getRDSTopLoad(ctx, piClient, &report.Instances[i], timeParams)
+		}
+	}
+
+	return report, nil
+}
+
+// Helper function to describe the instances of the report with a single
+// paginated call for the whole cluster
+func describeRDSInstances(ctx context.Context, rdsClient *rds.Client, clusterID string, instances []utils.RDSInstance) error {
+	input := &rds.DescribeDBInstancesInput{}
+	if clusterID != "" {
+		input.Filters = []rdsTypes.Filter{
+			{
+				Name:   aws.String("db-cluster-id"),
+				Values: []string{clusterID},
+			},
+		}
+	} else if len(instances) == 1 {
+		input.DBInstanceIdentifier = aws.String(instances[0].InstanceID)
+	} else {
+		return nil
+	}
+
+	var dbInstances []rdsTypes.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(rdsClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe DB instances: %w", err)
+		}
+		dbInstances = append(dbInstances, output.DBInstances...)
+	}
+
+	setRDSInstanceDetails(instances, dbInstances)
+	return nil
+}
+
+// Helper function to copy the details of the described DB instances to the
+// matching instances of the report. A cluster can mix serverless and
+// provisioned members, so Serverless comes from the class of each instance.
+// ResourceID is kept for the Performance Insights queries.
+func setRDSInstanceDetails(instances []utils.RDSInstance, dbInstances []rdsTypes.DBInstance) {
+	byID := make(map[string]rdsTypes.DBInstance, len(dbInstances))
+	for _, dbInstance := range dbInstances {
+		byID[aws.ToString(dbInstance.DBInstanceIdentifier)] = dbInstance
+	}
+
+	for i := range instances {
+		dbInstance := byID[instances[i].InstanceID]
+		instances[i].Serverless = aws.ToString(dbInstance.DBInstanceClass) == "db.serverless"
+		if aws.ToBool(dbInstance.PerformanceInsightsEnabled) {
+			instances[i].ResourceID = aws.ToString(dbInstance.DbiResourceId)
+		}
+	}
+}
+
+// Helper function to get the RDS events of the cluster and its instances,
+// oldest first. Each source is queried on its own so the API only returns
+// the events of the monitored cluster and instances.
+func getRDSEvents(ctx context.Context, rdsClient *rds.Client, report *utils.RDSReport, timeParams map[string]time.Time) ([]utils.RDSEvent, error) {
+	type rdsEventSource struct {
+		Type rdsTypes.SourceType
+		ID   string
+	}
+
+	var sources []rdsEventSource
+	if report.ClusterID != "" {
+		sources = append(sources, rdsEventSource{rdsTypes.SourceTypeDbCluster, report.ClusterID})
+	}
+	for _, instance := range report.Instances {
+		sources = append(sources, rdsEventSource{rdsTypes.SourceTypeDbInstance, instance.InstanceID})
+	}
+
+	var events []utils.RDSEvent
+	for _, source := range sources {
+		input := &rds.DescribeEventsInput{
+			SourceType:       source.Type,
+			SourceIdentifier: aws.String(source.ID),
+			StartTime:        aws.Time(timeParams["startTime"]),
+			EndTime:          aws.Time(timeParams["endTime"]),
+		}
 
-			result, err := cwClient.GetMetricStatistics(ctx, input)
+		paginator := rds.NewDescribeEventsPaginator(rdsClient, input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
 			if err != nil {
-				utils.Logger.Error("Failed to get Aurora instance metric",
-					zap.Error(err),
-					zap.String("metricName", metric.Name),
-					zap.String("statistic", metric.Statistic),
-					zap.String("clusterID", clusterID),
-					zap.Int32("period", *period),
-				)
-				continue
+				return nil, fmt.Errorf("failed to describe RDS events of %s: %w", source.ID, err)
 			}
 
-			metricKey := fmt.Sprintf("Instance_%s", metric.Name)
-			if metric.Name == "CPUUtilization" {
-				metricKey = fmt.Sprintf("Instance_CPUUtilization_%s", metric.Statistic)
+			for _, event := range output.Events {
+				events = append(events, utils.RDSEvent{
+					Time:       aws.ToTime(event.Date),
+					SourceID:   aws.ToString(event.SourceIdentifier),
+					Categories: event.EventCategories,
+					Message:    aws.ToString(event.Message),
+				})
 			}
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
 
-			if len(result.Datapoints) > 0 {
-				var value float64
-				switch metric.Statistic {
-				case "Average":
-					value = *result.Datapoints[0].Average
-				case "Maximum":
-					value = *result.Datapoints[0].Maximum
-				case "Sum":
-					value = *result.Datapoints[0].Sum
-				}
-
-				if metric.Name == "FreeableMemory" {
-					value = value / (1024.0 * 1024.0 * 1024.0)
-				}
-
-				if metric.Name == "ReadLatency" || metric.Name == "WriteLatency" {
-					value = value * 1000.0
-				}
-
-				metrics[metricKey] = value
-			} else {
-				metrics[metricKey] = 0.0
+	return events, nil
+}
+
+// Helper function to get the top SQL statements and wait events by DB load
+// from Performance Insights. Instances without Performance Insights are skipped.
+func getRDSTopLoad(ctx context.Context, piClient *pi.Client, instance *utils.RDSInstance, timeParams map[string]time.Time) {
+	if instance.ResourceID == "" {
+		return
+	}
+
+	groups := []struct {
+		Group     string
+		Dimension string
+		Target    *[]utils.RDSLoadItem
+	}{
+		{"db.sql_tokenized", "db.sql_tokenized.statement", &instance.TopSQL},
+		{"db.wait_event", "db.wait_event.name", &instance.TopWaits},
+	}
+
+	for _, group := range groups {
+		input := &pi.DescribeDimensionKeysInput{
+			ServiceType: piTypes.ServiceTypeRds,
+			Identifier:  aws.String(instance.ResourceID),
+			Metric:      aws.String("db.load.avg"),
+			StartTime:   aws.Time(timeParams["startTime"]),
+			EndTime:     aws.Time(timeParams["endTime"]),
+			GroupBy: &piTypes.DimensionGroup{
+				Group: aws.String(group.Group),
+				Limit: aws.Int32(rdsTopItems),
+			},
+		}
+
+		output, err := piClient.DescribeDimensionKeys(ctx, input)
+		if err != nil {
+			utils.Logger.Error("Failed to get Performance Insights dimension keys",
+				zap.Error(err),
+				zap.String("group", group.Group),
+				zap.String("instanceID", instance.InstanceID),
+			)
+			continue
+		}
+
+		for _, key := range output.Keys {
+			// Collapse multi-line SQL into a single line
+			name := strings.Join(strings.Fields(key.Dimensions[group.Dimension]), " ")
+			if name == "" {
+				continue
 			}
+			*group.Target = append(*group.Target, utils.RDSLoadItem{
+				Name: name,
+				Load: aws.ToFloat64(key.Total),
+			})
 		}
 	}
+}
 
-	// Cluster-level metrics (for the entire Aurora cluster)
-	if clusterID != "" {
+func rdsInstanceMetrics(ctx context.Context, cwClient *cloudwatch.Client, instance utils.RDSInstance, period *int32, timeParams map[string]time.Time) map[string]float64 {
+	metrics := map[string]float64{}
+
+	type rdsMetric struct {
+		Name      string
+		Statistic string
+		Unit      string
+	}
+
+	instanceMetrics := []rdsMetric{
+		{"CPUUtilization", "Average", "%"},
+		{"CPUUtilization", "Maximum", "%"},
+		{"FreeableMemory", "Average", "bytes"},
+		{"DatabaseConnections", "Maximum", "count"},
+		{"ReadLatency", "Average", "seconds"},
+		{"WriteLatency", "Average", "seconds"},
+	}
+
+	if instance.Role == "reader" {
+		instanceMetrics = append(instanceMetrics, rdsMetric{"AuroraReplicaLag", "Maximum", "ms"})
+	}
 
-		clusterMetrics := []struct {
-			Name      string
-			Statistic string
-			Unit      string
-		}{
-			{"VolumeBytesUsed", "Average", "bytes"},
-			{"VolumeReadIOPs", "Average", "count/5min"},
-			{"VolumeWriteIOPs", "Average", "count/5min"},
-		}
-
-		for _, metric := range clusterMetrics {
-			input := &cloudwatch.GetMetricStatisticsInput{
-				Namespace:  aws.String("AWS/RDS"),
-				MetricName: aws.String(metric.Name),
-				Dimensions: []types.Dimension{
-					{
-						Name:  aws.String("DBClusterIdentifier"),
-						Value: aws.String(clusterID),
-					},
+	// Aurora Serverless v2 capacity
+	if instance.Serverless {
+		instanceMetrics = append(instanceMetrics,
+			rdsMetric{"ACUUtilization", "Average", "%"},
+			rdsMetric{"ServerlessDatabaseCapacity", "Average", "ACU"},
+		)
+	}
+
+	for _, metric := range instanceMetrics {
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/RDS"),
+			MetricName: aws.String(metric.Name),
+			Dimensions: []types.Dimension{
+				{
+					Name:  aws.String("DBInstanceIdentifier"),
+					Value: aws.String(instance.InstanceID),
 				},
-				StartTime:  aws.Time(timeParams["startTime"]),
-				EndTime:    aws.Time(timeParams["endTime"]),
-				Period:     period,
-				Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+			},
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			utils.Logger.Error("Failed to get Aurora instance metric",
+				zap.Error(err),
+				zap.String("metricName", metric.Name),
+				zap.String("statistic", metric.Statistic),
+				zap.String("instanceID", instance.InstanceID),
+				zap.Int32("period", *period),
+			)
+			continue
+		}
+
+		metricKey := metric.Name
+		if metric.Name == "CPUUtilization" {
+			metricKey = fmt.Sprintf("CPUUtilization_%s", metric.Statistic)
+		}
+
+		if len(result.Datapoints) > 0 {
+			var value float64
+			switch metric.Statistic {
+			case "Average":
+				value = *result.Datapoints[0].Average
+			case "Maximum":
+				value = *result.Datapoints[0].Maximum
+			case "Sum":
+				value = *result.Datapoints[0].Sum
 			}
 
-			result, err := cwClient.GetMetricStatistics(ctx, input)
-			if err != nil {
-				utils.Logger.Error("Failed to get Aurora cluster metric",
-					zap.Error(err),
-					zap.String("metricName", metric.Name),
-					zap.String("statistic", metric.Statistic),
-					zap.String("clusterID", clusterID),
-					zap.Int32("period", *period),
-				)
-				continue
+			if metric.Name == "FreeableMemory" {
+				value = value / (1024.0 * 1024.0 * 1024.0)
 			}
 
-			metricKey := fmt.Sprintf("Cluster_%s", metric.Name)
+			if metric.Name == "ReadLatency" || metric.Name == "WriteLatency" {
+				value = value * 1000.0
+			}
+
+			metrics[metricKey] = value
+		} else {
+			metrics[metricKey] = 0.0
+		}
+	}
+
+	return metrics
+}
+
+func rdsClusterMetrics(ctx context.Context, cwClient *cloudwatch.Client, clusterID string, period *int32, timeParams map[string]time.Time) map[string]float64 {
+	metrics := map[string]float64{}
+
+	clusterMetrics := []struct {
+		Name      string
+		Statistic string
+		Unit      string
+	}{
+		{"VolumeBytesUsed", "Average", "bytes"},
+		{"VolumeReadIOPs", "Average", "count/5min"},
+		{"VolumeWriteIOPs", "Average", "count/5min"},
+	}
+
+	for _, metric := range clusterMetrics {
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/RDS"),
+			MetricName: aws.String(metric.Name),
+			Dimensions: []types.Dimension{
+				{
+					Name:  aws.String("DBClusterIdentifier"),
+					Value: aws.String(clusterID),
+				},
+			},
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
 
-			if len(result.Datapoints) > 0 {
-				var value float64
-				switch metric.Statistic {
-				case "Average":
-					value = *result.Datapoints[0].Average
-				case "Maximum":
-					value = *result.Datapoints[0].Maximum
-				}
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			utils.Logger.Error("Failed to get Aurora cluster metric",
+				zap.Error(err),
+				zap.String("metricName", metric.Name),
+				zap.String("statistic", metric.Statistic),
+				zap.String("clusterID", clusterID),
+				zap.Int32("period", *period),
+			)
+			continue
+		}
 
-				if strings.Contains(metric.Name, "Storage") || metric.Name == "VolumeBytesUsed" {
-					value = value / (1024.0 * 1024.0 * 1024.0)
-				}
+		metricKey := metric.Name
 
-				if strings.Contains(metric.Name, "Throughput") {
-					value = value / (1024.0 * 1024.0)
-				}
+		if len(result.Datapoints) > 0 {
+			var value float64
+			switch metric.Statistic {
+			case "Average":
+				value = *result.Datapoints[0].Average
+			case "Maximum":
+				value = *result.Datapoints[0].Maximum
+			}
 
-				metrics[metricKey] = value
-			} else {
-				metrics[metricKey] = 0.0
+			if strings.Contains(metric.Name, "Storage") || metric.Name == "VolumeBytesUsed" {
+				value = value / (1024.0 * 1024.0 * 1024.0)
 			}
+
+			if strings.Contains(metric.Name, "Throughput") {
+				value = value / (1024.0 * 1024.0)
+			}
+
+			metrics[metricKey] = value
+		} else {
+			metrics[metricKey] = 0.0
 		}
 	}
 
-	return metrics, nil
+	return metrics
 }
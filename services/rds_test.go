@@ -0,0 +1,90 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+func TestRDSClusterMembers(t *testing.T) {
+	member := func(id string, writer bool) rdsTypes.DBClusterMember {
+		return rdsTypes.DBClusterMember{DBInstanceIdentifier: aws.String(id), IsClusterWriter: aws.Bool(writer)}
+	}
+
+	tests := []struct {
+		name    string
+		members []rdsTypes.DBClusterMember
+		want    []utils.RDSInstance
+	}{
+		{
+			name: "no members",
+		},
+		{
+			name:    "writer first",
+			members: []rdsTypes.DBClusterMember{member("reader-1", false), member("writer", true), member("reader-2", false)},
+			want: []utils.RDSInstance{
+				{InstanceID: "writer", Role: "writer"},
+				{InstanceID: "reader-1", Role: "reader"},
+				{InstanceID: "reader-2", Role: "reader"},
+			},
+		},
+		{
+			name:    "members without identifier skipped",
+			members: []rdsTypes.DBClusterMember{{IsClusterWriter: aws.Bool(true)}, member("reader-1", false)},
+			want:    []utils.RDSInstance{{InstanceID: "reader-1", Role: "reader"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rdsClusterMembers(tt.members); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rdsClusterMembers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRDSInstanceDetails(t *testing.T) {
+	dbInstances := []rdsTypes.DBInstance{
+		{
+			DBInstanceIdentifier:       aws.String("writer"),
+			DBInstanceClass:            aws.String("db.r6g.large"),
+			DbiResourceId:              aws.String("db-WRITER"),
+			PerformanceInsightsEnabled: aws.Bool(true),
+		},
+		{
+			DBInstanceIdentifier:       aws.String("reader-1"),
+			DBInstanceClass:            aws.String("db.serverless"),
+			DbiResourceId:              aws.String("db-READER1"),
+			PerformanceInsightsEnabled: aws.Bool(false),
+		},
+	}
+
+	tests := []struct {
+		name           string
+		instanceID     string
+		wantServerless bool
+		wantResourceID string
+	}{
+		{"provisioned with Performance Insights", "writer", false, "db-WRITER"},
+		{"serverless v2 without Performance Insights", "reader-1", true, ""},
+		{"not described", "reader-2", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := []utils.RDSInstance{{InstanceID: tt.instanceID, Serverless: !tt.wantServerless}}
+			setRDSInstanceDetails(instances, dbInstances)
+
+			if got := instances[0].Serverless; got != tt.wantServerless {
+				t.Errorf("Serverless = %v, want %v", got, tt.wantServerless)
+			}
+			if got := instances[0].ResourceID; got != tt.wantResourceID {
+				t.Errorf("ResourceID = %q, want %q", got, tt.wantResourceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"go.uber.org/zap"
+)
+
+// ListTagsForResources accepts at most 10 health check IDs per request
+const route53TagsBatchSize = 10
+
+// GetRoute53HealthChecks returns the given health checks, or every health
+// check of the account when discover is set, named after their Name tag
+func GetRoute53HealthChecks(ctx context.Context, r53Client *route53.Client, healthCheckIDs []string, discover bool) ([]*utils.Route53HealthCheck, error) {
+	var healthChecks []r53Types.HealthCheck
+	if discover {
+		paginator := route53.NewListHealthChecksPaginator(r53Client, &route53.ListHealthChecksInput{})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list health checks: %w", err)
+			}
+			healthChecks = append(healthChecks, output.HealthChecks...)
+		}
+	} else {
+		for _, id := range healthCheckIDs {
+			output, err := r53Client.GetHealthCheck(ctx, &route53.GetHealthCheckInput{
+				HealthCheckId: aws.String(id),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get health check %s: %w", id, err)
+			}
+			healthChecks = append(healthChecks, *output.HealthCheck)
+		}
+	}
+
+	var reports []*utils.Route53HealthCheck
+	byID := map[string]*utils.Route53HealthCheck{}
+	for _, healthCheck := range healthChecks {
+		report := newRoute53HealthCheck(healthCheck)
+		reports = append(reports, report)
+		byID[report.ID] = report
+	}
+
+	for start := 0; start < len(reports); start += route53TagsBatchSize {
+		end := min(start+route53TagsBatchSize, len(reports))
+
+		var ids []string
+		for _, report := range reports[start:end] {
+			ids = append(ids, report.ID)
+		}
+
+		output, err := r53Client.ListTagsForResources(ctx, &route53.ListTagsForResourcesInput{
+			ResourceType: r53Types.TagResourceTypeHealthcheck,
+			ResourceIds:  ids,
+		})
+		if err != nil {
+			utils.Logger.Error("Failed to get health check tags", zap.Error(err))
+			continue
+		}
+
+		for _, tagSet := range output.ResourceTagSets {
+			report, ok := byID[aws.ToString(tagSet.ResourceId)]
+			if !ok {
+				continue
+			}
+			for _, tag := range tagSet.Tags {
+				if aws.ToString(tag.Key) == "Name" {
+					report.Name = aws.ToString(tag.Value)
+				}
+			}
+		}
+	}
+
+	return reports, nil
+}
+
+// Helper function to convert a health check into its report, with the
+// endpoint built from the domain name (or IP address) and resource path
+func newRoute53HealthCheck(healthCheck r53Types.HealthCheck) *utils.Route53HealthCheck {
+	report := &utils.Route53HealthCheck{
+		ID:      aws.ToString(healthCheck.Id),
+		Metrics: map[string]float64{},
+	}
+	if config := healthCheck.HealthCheckConfig; config != nil {
+		report.Type = string(config.Type)
+		report.Endpoint = aws.ToString(config.FullyQualifiedDomainName)
+		if report.Endpoint == "" {
+			report.Endpoint = aws.ToString(config.IPAddress)
+		}
+		report.Endpoint += aws.ToString(config.ResourcePath)
+		report.MeasureLatency = aws.ToBool(config.MeasureLatency)
+	}
+	return report
+}
+
+// Route53Metrics fills the status metrics of a health check, plus the latency
+// metrics when MeasureLatency is enabled. cwClient must be a us-east-1 client.
+// HealthCheckStatus_Minimum is 0 if the check failed at any point.
+func Route53Metrics(ctx context.Context, cwClient *cloudwatch.Client, healthCheck *utils.Route53HealthCheck, timeParams map[string]time.Time) error {
+	period := aws.Int32(3600)
+	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
+		period = aws.Int32(86400)
+	}
+
+	r53Metrics := []struct {
+		Name      string
+		Statistic string
+		Latency   bool
+	}{
+		{"HealthCheckStatus", "Minimum", false},
+		{"HealthCheckPercentageHealthy", "Average", false},
+		{"ConnectionTime", "Average", true},
+		{"TimeToFirstByte", "Average", true},
+	}
+
+	for _, metric := range r53Metrics {
+		if metric.Latency && !healthCheck.MeasureLatency {
+			continue
+		}
+
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String("AWS/Route53"),
+			MetricName: aws.String(metric.Name),
+			Dimensions: []types.Dimension{
+				{
+					Name:  aws.String("HealthCheckId"),
+					Value: aws.String(healthCheck.ID),
+				},
+			},
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			return fmt.Errorf("error getting %s: %v", metric.Name, err)
+		}
+
+		key := fmt.Sprintf("%s_%s", metric.Name, metric.Statistic)
+		if len(result.Datapoints) == 0 {
+			healthCheck.Metrics[key] = 0.0
+			continue
+		}
+		if metric.Name == "HealthCheckStatus" {
+			healthCheck.HasStatus = true
+		}
+		switch metric.Statistic {
+		case "Minimum":
+			healthCheck.Metrics[key] = aws.ToFloat64(result.Datapoints[0].Minimum)
+		case "Average":
+			healthCheck.Metrics[key] = aws.ToFloat64(result.Datapoints[0].Average)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+func TestNewRoute53HealthCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *r53Types.HealthCheckConfig
+		want   *utils.Route53HealthCheck
+	}{
+		{
+			name: "domain name and path with latency",
+			config: &r53Types.HealthCheckConfig{
+				Type:                     r53Types.HealthCheckTypeHttps,
+				FullyQualifiedDomainName: aws.String("api.example.com"),
+				IPAddress:                aws.String("192.0.2.10"),
+				ResourcePath:             aws.String("/health"),
+				MeasureLatency:           aws.Bool(true),
+			},
+			want: &utils.Route53HealthCheck{ID: "hc-1", Type: "HTTPS", Endpoint: "api.example.com/health", MeasureLatency: true},
+		},
+		{
+			name: "IP address",
+			config: &r53Types.HealthCheckConfig{
+				Type:      r53Types.HealthCheckTypeTcp,
+				IPAddress: aws.String("192.0.2.10"),
+			},
+			want: &utils.Route53HealthCheck{ID: "hc-1", Type: "TCP", Endpoint: "192.0.2.10"},
+		},
+		{
+			name:   "calculated",
+			config: &r53Types.HealthCheckConfig{Type: r53Types.HealthCheckTypeCalculated},
+			want:   &utils.Route53HealthCheck{ID: "hc-1", Type: "CALCULATED"},
+		},
+		{
+			name: "no config",
+			want: &utils.Route53HealthCheck{ID: "hc-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Metrics = map[string]float64{}
+			got := newRoute53HealthCheck(r53Types.HealthCheck{Id: aws.String("hc-1"), HealthCheckConfig: tt.config})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newRoute53HealthCheck() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,87 +2,155 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"telegraws/utils"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingTypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"go.uber.org/zap"
 )
 
-func S3Metrics(ctx context.Context, cwClient *cloudwatch.Client, bucketName string, timeParams map[string]time.Time) (map[string]float64, error) {
-	metrics := map[string]float64{}
+// DiscoverS3Buckets returns the names of the buckets matching all the given tags
+func DiscoverS3Buckets(ctx context.Context, taggingClient *resourcegroupstaggingapi.Client, tags map[string]string) ([]string, error) {
+	var tagFilters []taggingTypes.TagFilter
+	for key, value := range tags {
+		tagFilters = append(tagFilters, taggingTypes.TagFilter{
+			Key:    aws.String(key),
+			Values: []string{value},
+		})
+	}
+
+	input := &resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: []string{"s3"},
+		TagFilters:          tagFilters,
+	}
+
+	var bucketNames []string
+	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(taggingClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tagged S3 buckets: %w", err)
+		}
+
+		for _, resource := range output.ResourceTagMappingList {
+			// Bucket ARNs have the form arn:aws:s3:::bucket-name
+			arn := aws.ToString(resource.ResourceARN)
+			if idx := strings.LastIndex(arn, ":"); idx >= 0 && idx < len(arn)-1 {
+				bucketNames = append(bucketNames, arn[idx+1:])
+			}
+		}
+	}
+
+	return bucketNames, nil
+}
+
+func S3Metrics(ctx context.Context, cwClient *cloudwatch.Client, bucketName string, timeParams map[string]time.Time) (*utils.S3Bucket, error) {
+	bucket := &utils.S3Bucket{
+		BucketName:     bucketName,
+		StorageClasses: map[string]float64{},
+		Metrics:        map[string]float64{},
+	}
+
+	// Storage metrics are published once a day, so look back far enough to
+	// always get the latest two datapoints for the day-over-day growth
+	storageStart := timeParams["endTime"].Add(-72 * time.Hour)
 	period := aws.Int32(86400) // Always use daily for S3
 
-	// Storage metrics (daily reporting)
+	// Discover the storage classes that actually have data for this bucket
+	listInput := &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/S3"),
+		MetricName: aws.String("BucketSizeBytes"),
+		Dimensions: []types.DimensionFilter{
+			{
+				Name:  aws.String("BucketName"),
+				Value: aws.String(bucketName),
+			},
+		},
+	}
+
+	var storageTypes []string
+	listPaginator := cloudwatch.NewListMetricsPaginator(cwClient, listInput)
+	for listPaginator.HasMorePages() {
+		output, err := listPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing S3 storage metrics: %v", err)
+		}
+
+		for _, metric := range output.Metrics {
+			for _, dim := range metric.Dimensions {
+				if aws.ToString(dim.Name) == "StorageType" && dim.Value != nil {
+					storageTypes = append(storageTypes, *dim.Value)
+				}
+			}
+		}
+	}
+	sort.Strings(storageTypes)
+
 	storageMetrics := []struct {
 		Name         string
-		Statistic    string
-		StorageTypes []string // Try multiple storage types
+		StorageTypes []string
 	}{
-		{"BucketSizeBytes", "Average", []string{
-			"StandardStorage",
-			"StandardIAStorage",
-			"StandardIASizeOverhead",
-			"ReducedRedundancyStorage",
-			"GlacierStorage",
-			"DeepArchiveStorage",
-			"GlacierInstantRetrievalSizeOverhead",
-			"GlacierFlexibleRetrievalSizeOverhead",
-			"GlacierDeepArchiveSizeOverhead",
-			"IntelligentTieringFAStorage",
-			"IntelligentTieringIAStorage",
-			"IntelligentTieringAAStorage",
-			"IntelligentTieringAIAStorage",
-			"IntelligentTieringDAAStorage",
-		}},
+		{"BucketSizeBytes", storageTypes},
+		{"NumberOfObjects", []string{"AllStorageTypes"}},
 	}
 
-	// Try storage metrics with different storage types
+	// Sum every storage class, keeping the previous day to compute the growth
 	for _, metric := range storageMetrics {
-		found := false
 		for _, storageType := range metric.StorageTypes {
-			dimensions := []types.Dimension{
-				{
-					Name:  aws.String("BucketName"),
-					Value: aws.String(bucketName),
-				},
-				{
-					Name:  aws.String("StorageType"),
-					Value: aws.String(storageType),
-				},
-			}
-
 			input := &cloudwatch.GetMetricStatisticsInput{
 				Namespace:  aws.String("AWS/S3"),
 				MetricName: aws.String(metric.Name),
-				Dimensions: dimensions,
-				StartTime:  aws.Time(timeParams["startTime"]),
+				Dimensions: []types.Dimension{
+					{
+						Name:  aws.String("BucketName"),
+						Value: aws.String(bucketName),
+					},
+					{
+						Name:  aws.String("StorageType"),
+						Value: aws.String(storageType),
+					},
+				},
+				StartTime:  aws.Time(storageStart),
 				EndTime:    aws.Time(timeParams["endTime"]),
 				Period:     period,
-				Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+				Statistics: []types.Statistic{types.StatisticAverage},
 			}
 
 			result, err := cwClient.GetMetricStatistics(ctx, input)
 			if err != nil {
-				continue // Try next storage type
+				utils.Logger.Error("Failed to get S3 storage metric",
+					zap.Error(err),
+					zap.String("metricName", metric.Name),
+					zap.String("storageType", storageType),
+					zap.String("bucketName", bucketName),
+				)
+				continue
 			}
 
-			if len(result.Datapoints) > 0 && result.Datapoints[0].Average != nil {
-				value := *result.Datapoints[0].Average
+			latest, previous, hasPrevious := s3LatestAndPrevious(result.Datapoints)
 
-				if metric.Name == "BucketSizeBytes" {
-					value = value / (1024.0 * 1024.0)
+			// Without a prior datapoint the "_Previous" metric is left out so
+			// the growth is reported as unknown instead of zero
+			if metric.Name == "BucketSizeBytes" {
+				bucket.StorageClasses[storageType] = latest / (1024.0 * 1024.0)
+				bucket.Metrics["BucketSizeBytes"] += latest / (1024.0 * 1024.0)
+				if hasPrevious {
+					bucket.Metrics["BucketSizeBytes_Previous"] += previous / (1024.0 * 1024.0)
+				}
+			} else {
+				bucket.Metrics[metric.Name] = latest
+				if hasPrevious {
+					bucket.Metrics[metric.Name+"_Previous"] = previous
 				}
-
-				metrics[metric.Name] = value
-				found = true
-				break // Found data, stop trying other storage types
 			}
 		}
-
-		if !found {
-			metrics[metric.Name] = 0.0
-		}
 	}
 
 	// Request metrics (only if enabled in bucket - these are often 0)
@@ -121,16 +189,38 @@ func S3Metrics(ctx context.Context, cwClient *cloudwatch.Client, bucketName stri
 
 		result, err := cwClient.GetMetricStatistics(ctx, input)
 		if err != nil {
-			metrics[metric.Name] = 0.0
+			bucket.Metrics[metric.Name] = 0.0
 			continue
 		}
 
 		if len(result.Datapoints) > 0 && result.Datapoints[0].Sum != nil {
-			metrics[metric.Name] = *result.Datapoints[0].Sum
+			bucket.Metrics[metric.Name] = *result.Datapoints[0].Sum
 		} else {
-			metrics[metric.Name] = 0.0
+			bucket.Metrics[metric.Name] = 0.0
 		}
 	}
 
-	return metrics, nil
+	return bucket, nil
+}
+
+// Helper function to get the latest and the prior daily datapoint. A new
+// bucket or storage class only has the latest one, so hasPrevious is false.
+func s3LatestAndPrevious(datapoints []types.Datapoint) (latest, previous float64, hasPrevious bool) {
+	sorted := make([]types.Datapoint, 0, len(datapoints))
+	for _, datapoint := range datapoints {
+		if datapoint.Average != nil {
+			sorted = append(sorted, datapoint)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return aws.ToTime(sorted[i].Timestamp).After(aws.ToTime(sorted[j].Timestamp))
+	})
+
+	if len(sorted) > 0 {
+		latest = *sorted[0].Average
+	}
+	if len(sorted) > 1 {
+		return latest, *sorted[1].Average, true
+	}
+	return latest, 0, false
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+func TestS3LatestAndPrevious(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	datapoint := func(daysAgo int, average float64) types.Datapoint {
+		return types.Datapoint{Timestamp: aws.Time(day.AddDate(0, 0, -daysAgo)), Average: aws.Float64(average)}
+	}
+
+	tests := []struct {
+		name            string
+		datapoints      []types.Datapoint
+		wantLatest      float64
+		wantPrevious    float64
+		wantHasPrevious bool
+	}{
+		{
+			name: "no datapoints",
+		},
+		{
+			name:       "first datapoint",
+			datapoints: []types.Datapoint{datapoint(0, 100)},
+			wantLatest: 100,
+		},
+		{
+			name:            "newest first regardless of order",
+			datapoints:      []types.Datapoint{datapoint(2, 80), datapoint(0, 100), datapoint(1, 90)},
+			wantLatest:      100,
+			wantPrevious:    90,
+			wantHasPrevious: true,
+		},
+		{
+			name:       "datapoints without average skipped",
+			datapoints: []types.Datapoint{datapoint(0, 100), {Timestamp: aws.Time(day.AddDate(0, 0, -1))}},
+			wantLatest: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest, previous, hasPrevious := s3LatestAndPrevious(tt.datapoints)
+			if latest != tt.wantLatest || previous != tt.wantPrevious || hasPrevious != tt.wantHasPrevious {
+				t.Errorf("s3LatestAndPrevious() = %v, %v, %v, want %v, %v, %v",
+					latest, previous, hasPrevious, tt.wantLatest, tt.wantPrevious, tt.wantHasPrevious)
+			}
+		})
+	}
+}
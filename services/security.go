@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+	gdTypes "github.com/aws/aws-sdk-go-v2/service/guardduty/types"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	shTypes "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"go.uber.org/zap"
+)
+
+// GetFindings accepts at most 50 finding IDs per request
+const guardDutyFindingsBatchSize = 50
+
+// GuardDutyFindings returns the findings created during the report window by
+// the detector of the region, skipping archived ones. Severities are mapped
+// to the GuardDuty console labels.
+func GuardDutyFindings(ctx context.Context, gdClient *guardduty.Client, timeParams map[string]time.Time) ([]utils.SecurityFinding, error) {
+	detectorsOutput, err := gdClient.ListDetectors(ctx, &guardduty.ListDetectorsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GuardDuty detectors: %w", err)
+	}
+	if len(detectorsOutput.DetectorIds) == 0 {
+		return nil, fmt.Errorf("GuardDuty is not enabled in this region")
+	}
+	detectorID := detectorsOutput.DetectorIds[0]
+
+	input := &guardduty.ListFindingsInput{
+		DetectorId: aws.String(detectorID),
+		FindingCriteria: &gdTypes.FindingCriteria{
+			Criterion: map[string]gdTypes.Condition{
+				"updatedAt": {
+					GreaterThanOrEqual: aws.Int64(timeParams["startTime"].UnixMilli()),
+					LessThan:           aws.Int64(timeParams["endTime"].UnixMilli()),
+				},
+				"service.archived": {
+					Equals: []string{"false"},
+				},
+			},
+		},
+	}
+
+	var findingIDs []string
+	paginator := guardduty.NewListFindingsPaginator(gdClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list GuardDuty findings: %w", err)
+		}
+		findingIDs = append(findingIDs, output.FindingIds...)
+	}
+
+	var findings []utils.SecurityFinding
+	for start := 0; start < len(findingIDs); start += guardDutyFindingsBatchSize {
+		end := min(start+guardDutyFindingsBatchSize, len(findingIDs))
+
+		output, err := gdClient.GetFindings(ctx, &guardduty.GetFindingsInput{
+			DetectorId: aws.String(detectorID),
+			FindingIds: findingIDs[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get GuardDuty findings: %w", err)
+		}
+
+		for _, finding := range output.Findings {
+			// updatedAt also matches older findings seen again, keep only new ones
+			createdAt, err := time.Parse(time.RFC3339, aws.ToString(finding.CreatedAt))
+			if err == nil && createdAt.Before(timeParams["startTime"]) {
+				continue
+			}
+
+			findings = append(findings, utils.SecurityFinding{
+				Source:   "GuardDuty",
+				Severity: guardDutySeverityLabel(aws.ToFloat64(finding.Severity)),
+				Title:    aws.ToString(finding.Title),
+				Type:     aws.ToString(finding.Type),
+				Resource: guardDutyResource(finding.Resource),
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// SecurityHubFailedControls returns the active, unresolved Security Hub
+// control findings that started failing during the report window. Security
+// Hub updates a control's finding in place when it goes from PASSED to
+// FAILED, so findings updated in the window are kept when they were first
+// observed in it or their history shows the compliance status change. The
+// periodic re-evaluations of controls that were already failing also update
+// UpdatedAt and are skipped.
+func SecurityHubFailedControls(ctx context.Context, shClient *securityhub.Client, timeParams map[string]time.Time) ([]utils.SecurityFinding, error) {
+	equals := func(value string) []shTypes.StringFilter {
+		return []shTypes.StringFilter{{Comparison: shTypes.StringFilterComparisonEquals, Value: aws.String(value)}}
+	}
+
+	input := &securityhub.GetFindingsInput{
+		Filters: &shTypes.AwsSecurityFindingFilters{
+			ComplianceStatus: equals("FAILED"),
+			RecordState:      equals("ACTIVE"),
+			WorkflowStatus: append(equals(string(shTypes.WorkflowStatusNew)),
+				equals(string(shTypes.WorkflowStatusNotified))...),
+			UpdatedAt: []shTypes.DateFilter{
+				{
+					Start: aws.String(timeParams["startTime"].Format(time.RFC3339)),
+					End:   aws.String(timeParams["endTime"].Format(time.RFC3339)),
+				},
+			},
+		},
+		MaxResults: aws.Int32(100),
+	}
+
+	var findings []utils.SecurityFinding
+	paginator := securityhub.NewGetFindingsPaginator(shClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Security Hub findings: %w", err)
+		}
+
+		for _, finding := range output.Findings {
+			if !isNewlyFailedControl(ctx, shClient, finding, timeParams) {
+				continue
+			}
+
+			item := utils.SecurityFinding{
+				Source: "SecurityHub",
+				Title:  aws.ToString(finding.Title),
+			}
+			if finding.Severity != nil {
+				item.Severity = string(finding.Severity.Label)
+			}
+			if finding.Compliance != nil && finding.Compliance.SecurityControlId != nil {
+				item.Type = *finding.Compliance.SecurityControlId
+			} else if len(finding.Types) > 0 {
+				item.Type = finding.Types[0]
+			}
+			if len(finding.Resources) > 0 {
+				item.Resource = aws.ToString(finding.Resources[0].Id)
+			}
+
+			findings = append(findings, item)
+		}
+	}
+
+	return findings, nil
+}
+
+// Helper function to check that a failed control finding started failing
+// during the report window. When its history can't be read the finding is
+// reported, since missing a new failure is worse than repeating one.
+func isNewlyFailedControl(ctx context.Context, shClient *securityhub.Client, finding shTypes.AwsSecurityFinding, timeParams map[string]time.Time) bool {
+	firstObserved, err := time.Parse(time.RFC3339, aws.ToString(finding.FirstObservedAt))
+	if err == nil && !firstObserved.Before(timeParams["startTime"]) {
+		return true
+	}
+
+	input := &securityhub.GetFindingHistoryInput{
+		FindingIdentifier: &shTypes.AwsSecurityFindingIdentifier{
+			Id:         finding.Id,
+			ProductArn: finding.ProductArn,
+		},
+		StartTime: aws.Time(timeParams["startTime"]),
+		EndTime:   aws.Time(timeParams["endTime"]),
+	}
+
+	var records []shTypes.FindingHistoryRecord
+	paginator := securityhub.NewGetFindingHistoryPaginator(shClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			utils.Logger.Error("Failed to get Security Hub finding history",
+				zap.Error(err),
+				zap.String("findingID", aws.ToString(finding.Id)),
+			)
+			return true
+		}
+		records = append(records, output.Records...)
+	}
+
+	return complianceChangedToFailed(records)
+}
+
+// Helper function to check that a finding history has a change of the
+// compliance status to FAILED. The status is either its own field or part of
+// the Compliance object, and values are JSON encoded.
+func complianceChangedToFailed(records []shTypes.FindingHistoryRecord) bool {
+	for _, record := range records {
+		for _, update := range record.Updates {
+			field := aws.ToString(update.UpdatedField)
+			oldStatus := historyComplianceStatus(field, aws.ToString(update.OldValue))
+			newStatus := historyComplianceStatus(field, aws.ToString(update.NewValue))
+			if newStatus == string(shTypes.ComplianceStatusFailed) && oldStatus != newStatus {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Helper function to get the compliance status from a finding history value
+func historyComplianceStatus(field, value string) string {
+	switch field {
+	case "Compliance.Status":
+		return strings.Trim(value, `"`)
+	case "Compliance":
+		var compliance struct {
+			Status string
+		}
+		if err := json.Unmarshal([]byte(value), &compliance); err == nil {
+			return compliance.Status
+		}
+	}
+	return ""
+}
+
+// Helper function to map a GuardDuty severity score to its label
+func guardDutySeverityLabel(severity float64) string {
+	switch {
+	case severity >= 9:
+		return "CRITICAL"
+	case severity >= 7:
+		return "HIGH"
+	case severity >= 4:
+		return "MEDIUM"
+	default:
+		return "LOW"
+	}
+}
+
+// Helper function to describe the resource of a GuardDuty finding
+func guardDutyResource(resource *gdTypes.Resource) string {
+	if resource == nil {
+		return ""
+	}
+
+	resourceType := aws.ToString(resource.ResourceType)
+	switch {
+	case resource.InstanceDetails != nil && resource.InstanceDetails.InstanceId != nil:
+		return resourceType + " " + *resource.InstanceDetails.InstanceId
+	case resource.AccessKeyDetails != nil && resource.AccessKeyDetails.UserName != nil:
+		return resourceType + " " + *resource.AccessKeyDetails.UserName
+	case len(resource.S3BucketDetails) > 0 && resource.S3BucketDetails[0].Name != nil:
+		return resourceType + " " + *resource.S3BucketDetails[0].Name
+	case resource.LambdaDetails != nil && resource.LambdaDetails.FunctionName != nil:
+		return resourceType + " " + *resource.LambdaDetails.FunctionName
+	}
+	return resourceType
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	shTypes "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+)
+
+func TestGuardDutySeverityLabel(t *testing.T) {
+	tests := []struct {
+		severity float64
+		want     string
+	}{
+		{9.5, "CRITICAL"},
+		{9, "CRITICAL"},
+		{8.9, "HIGH"},
+		{7, "HIGH"},
+		{6.9, "MEDIUM"},
+		{4, "MEDIUM"},
+		{3.9, "LOW"},
+		{0, "LOW"},
+	}
+
+	for _, tt := range tests {
+		if got := guardDutySeverityLabel(tt.severity); got != tt.want {
+			t.Errorf("guardDutySeverityLabel(%v) = %s, want %s", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestComplianceChangedToFailed(t *testing.T) {
+	update := func(field, oldValue, newValue string) shTypes.FindingHistoryUpdate {
+		return shTypes.FindingHistoryUpdate{
+			UpdatedField: aws.String(field),
+			OldValue:     aws.String(oldValue),
+			NewValue:     aws.String(newValue),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		updates []shTypes.FindingHistoryUpdate
+		want    bool
+	}{
+		{
+			name: "no history",
+		},
+		{
+			name:    "PASSED to FAILED",
+			updates: []shTypes.FindingHistoryUpdate{update("Compliance.Status", `"PASSED"`, `"FAILED"`)},
+			want:    true,
+		},
+		{
+			name: "PASSED to FAILED in the Compliance object",
+			updates: []shTypes.FindingHistoryUpdate{
+				update("Compliance", `{"Status":"PASSED"}`, `{"Status":"FAILED","StatusReasons":[{"ReasonCode":"CONFIG_EVALUATIONS_EMPTY"}]}`),
+			},
+			want: true,
+		},
+		{
+			name: "already failing control re-evaluated",
+			updates: []shTypes.FindingHistoryUpdate{
+				update("Compliance", `{"Status":"FAILED"}`, `{"Status":"FAILED","StatusReasons":[{"ReasonCode":"CONFIG_EVALUATIONS_EMPTY"}]}`),
+			},
+		},
+		{
+			name:    "FAILED to PASSED",
+			updates: []shTypes.FindingHistoryUpdate{update("Compliance.Status", `"FAILED"`, `"PASSED"`)},
+		},
+		{
+			name: "other fields",
+			updates: []shTypes.FindingHistoryUpdate{
+				update("Workflow.Status", `"NEW"`, `"NOTIFIED"`),
+				update("Severity.Label", `"LOW"`, `"FAILED"`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []shTypes.FindingHistoryRecord{{Updates: tt.updates}}
+			if got := complianceChangedToFailed(records); got != tt.want {
+				t.Errorf("complianceChangedToFailed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
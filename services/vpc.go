@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"telegraws/utils"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// NAT gateway data processing list price per GB in us-east-1
+const natGatewayPricePerGB = 0.045
+
+// Metric of a VPC networking resource. Bytes are converted to GB.
+type vpcMetric struct {
+	Name      string
+	Statistic string
+	Unit      string
+}
+
+// GetNATGateways returns the given NAT gateways, or every available NAT
+// gateway of the region when discover is set, named after their Name tag
+func GetNATGateways(ctx context.Context, ec2Client *ec2.Client, natGatewayIDs []string, discover bool) ([]*utils.NATGateway, error) {
+	input := &ec2.DescribeNatGatewaysInput{}
+	if discover {
+		input.Filter = []ec2Types.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: []string{string(ec2Types.NatGatewayStateAvailable)},
+			},
+		}
+	} else {
+		input.NatGatewayIds = natGatewayIDs
+	}
+
+	var natGateways []*utils.NATGateway
+	paginator := ec2.NewDescribeNatGatewaysPaginator(ec2Client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe NAT gateways: %w", err)
+		}
+
+		for _, natGateway := range output.NatGateways {
+			report := &utils.NATGateway{
+				NATGatewayID: aws.ToString(natGateway.NatGatewayId),
+				VpcID:        aws.ToString(natGateway.VpcId),
+				Metrics:      map[string]float64{},
+			}
+			for _, tag := range natGateway.Tags {
+				if aws.ToString(tag.Key) == "Name" {
+					report.Name = aws.ToString(tag.Value)
+				}
+			}
+			natGateways = append(natGateways, report)
+		}
+	}
+
+	return natGateways, nil
+}
+
+// NATGatewayMetrics fills the metrics of a NAT gateway. ErrorPortAllocation
+// counts connections that failed because the gateway ran out of source ports
+// for a single destination. ProcessingCost is estimated from the bytes
+// received from both sides, which is what AWS bills as data processed.
+func NATGatewayMetrics(ctx context.Context, cwClient *cloudwatch.Client, natGateway *utils.NATGateway, timeParams map[string]time.Time) error {
+	natMetrics := []vpcMetric{
+		{"ErrorPortAllocation", "Sum", "count"},
+		{"PacketsDropCount", "Sum", "count"},
+		{"ActiveConnectionCount", "Maximum", "count"},
+		{"BytesOutToDestination", "Sum", "GB"},
+		{"BytesInFromSource", "Sum", "GB"},
+		{"BytesInFromDestination", "Sum", "GB"},
+	}
+
+	dimension := types.Dimension{
+		Name:  aws.String("NatGatewayId"),
+		Value: aws.String(natGateway.NATGatewayID),
+	}
+	if err := getVPCMetrics(ctx, cwClient, "AWS/NATGateway", dimension, natMetrics, natGateway.Metrics, timeParams); err != nil {
+		return err
+	}
+
+	natGateway.ProcessingCost = natGatewayProcessingCost(natGateway.Metrics)
+
+	return nil
+}
+
+// Helper function to estimate the data processing cost of a NAT gateway from
+// its metrics in GB. Traffic is billed once in each direction.
+func natGatewayProcessingCost(metrics map[string]float64) float64 {
+	processedGB := metrics["BytesInFromSource"] + metrics["BytesInFromDestination"]
+	return processedGB * natGatewayPricePerGB
+}
+
+// VPNTunnels returns the current state of both tunnels of the given VPN
+// connections, as reported by the VPN telemetry
+func VPNTunnels(ctx context.Context, ec2Client *ec2.Client, vpnConnectionIDs []string) ([]utils.VPNTunnel, error) {
+	output, err := ec2Client.DescribeVpnConnections(ctx, &ec2.DescribeVpnConnectionsInput{
+		VpnConnectionIds: vpnConnectionIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe VPN connections: %w", err)
+	}
+
+	var tunnels []utils.VPNTunnel
+	for _, connection := range output.VpnConnections {
+		var name string
+		for _, tag := range connection.Tags {
+			if aws.ToString(tag.Key) == "Name" {
+				name = aws.ToString(tag.Value)
+			}
+		}
+
+		for _, telemetry := range connection.VgwTelemetry {
+			tunnels = append(tunnels, utils.VPNTunnel{
+				VPNConnectionID:  aws.ToString(connection.VpnConnectionId),
+				Name:             name,
+				OutsideIP:        aws.ToString(telemetry.OutsideIpAddress),
+				Status:           string(telemetry.Status),
+				StatusMessage:    aws.ToString(telemetry.StatusMessage),
+				LastStatusChange: aws.ToTime(telemetry.LastStatusChange),
+			})
+		}
+	}
+
+	return tunnels, nil
+}
+
+// TransitGatewayMetrics returns the traffic of a transit gateway and the
+// packets it dropped because they matched a blackhole route or no route
+func TransitGatewayMetrics(ctx context.Context, cwClient *cloudwatch.Client, transitGatewayID string, timeParams map[string]time.Time) (*utils.TransitGateway, error) {
+	tgwMetrics := []vpcMetric{
+		{"BytesIn", "Sum", "GB"},
+		{"BytesOut", "Sum", "GB"},
+		{"PacketDropCountBlackhole", "Sum", "count"},
+		{"PacketDropCountNoRoute", "Sum", "count"},
+	}
+
+	transitGateway := &utils.TransitGateway{
+		TransitGatewayID: transitGatewayID,
+		Metrics:          map[string]float64{},
+	}
+
+	dimension := types.Dimension{
+		Name:  aws.String("TransitGateway"),
+		Value: aws.String(transitGatewayID),
+	}
+	if err := getVPCMetrics(ctx, cwClient, "AWS/TransitGateway", dimension, tgwMetrics, transitGateway.Metrics, timeParams); err != nil {
+		return nil, err
+	}
+
+	return transitGateway, nil
+}
+
+// Helper function to get the metrics of a NAT or transit gateway into metricsMap
+func getVPCMetrics(ctx context.Context, cwClient *cloudwatch.Client, namespace string, dimension types.Dimension, vpcMetrics []vpcMetric, metricsMap map[string]float64, timeParams map[string]time.Time) error {
+	period := aws.Int32(3600)
+	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
+		period = aws.Int32(86400)
+	}
+
+	for _, metric := range vpcMetrics {
+		input := &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String(namespace),
+			MetricName: aws.String(metric.Name),
+			Dimensions: []types.Dimension{dimension},
+			StartTime:  aws.Time(timeParams["startTime"]),
+			EndTime:    aws.Time(timeParams["endTime"]),
+			Period:     period,
+			Statistics: []types.Statistic{types.Statistic(metric.Statistic)},
+		}
+
+		result, err := cwClient.GetMetricStatistics(ctx, input)
+		if err != nil {
+			return fmt.Errorf("error getting %s: %v", metric.Name, err)
+		}
+
+		if len(result.Datapoints) == 0 {
+			metricsMap[metric.Name] = 0.0
+			continue
+		}
+
+		var value float64
+		switch metric.Statistic {
+		case "Sum":
+			value = aws.ToFloat64(result.Datapoints[0].Sum)
+		case "Maximum":
+			value = aws.ToFloat64(result.Datapoints[0].Maximum)
+		}
+		if metric.Unit == "GB" {
+			value = value / (1024.0 * 1024.0 * 1024.0)
+		}
+		metricsMap[metric.Name] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNATGatewayProcessingCost(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics map[string]float64
+		want    float64
+	}{
+		{"no traffic", map[string]float64{}, 0},
+		{"both directions", map[string]float64{"BytesInFromSource": 60, "BytesInFromDestination": 40}, 4.5},
+		{
+			// BytesOutToDestination is the same traffic as BytesInFromSource
+			name:    "outbound bytes not counted twice",
+			metrics: map[string]float64{"BytesInFromSource": 10, "BytesOutToDestination": 10},
+			want:    0.45,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := natGatewayProcessingCost(tt.metrics); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("natGatewayProcessingCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
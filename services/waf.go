@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"telegraws/utils"
 	"time"
 
@@ -14,46 +16,258 @@ import (
 	"go.uber.org/zap"
 )
 
-// Helper function to get ALB ARN from WAF
-func getALBARNFromWAF(ctx context.Context, wafClient *wafv2.Client, webACLName, webACLId string) (string, error) {
-	webACLInput := &wafv2.GetWebACLInput{
+// Regional resource types a web ACL can protect, with the ResourceType
+// dimension of their per-resource metrics and the label shown in reports
+var wafResourceTypes = []struct {
+	ResourceType wafTypes.ResourceType
+	Dimension    string
+	Label        string
+}{
+	{wafTypes.ResourceTypeApplicationLoadBalancer, "ALB", "ALB"},
+	{wafTypes.ResourceTypeApiGateway, "ApiGateway", "API Gateway"},
+	{wafTypes.ResourceTypeAppsync, "AppSync", "AppSync"},
+	{wafTypes.ResourceTypeCognitioUserPool, "CognitoUserPool", "Cognito"},
+	{wafTypes.ResourceTypeAppRunnerService, "AppRunnerService", "App Runner"},
+	{wafTypes.ResourceTypeVerifiedAccessInstance, "VerifiedAccessInstance", "Verified Access"},
+}
+
+// Resource associated with a regional web ACL
+type wafResource struct {
+	ARN       string
+	Dimension string
+	Label     string
+}
+
+// Helper function to get the resources of every regional type associated
+// with a web ACL. A type that fails to list is logged and skipped.
+func getWAFResources(ctx context.Context, wafClient *wafv2.Client, webACLArn *string, webACLName string) []wafResource {
+	var resources []wafResource
+	for _, resourceType := range wafResourceTypes {
+		resourcesOutput, err := wafClient.ListResourcesForWebACL(ctx, &wafv2.ListResourcesForWebACLInput{
+			WebACLArn:    webACLArn,
+			ResourceType: resourceType.ResourceType,
+		})
+		if err != nil {
+			utils.Logger.Error("Failed to get resources associated with WAF",
+				zap.Error(err),
+				zap.String("webACLName", webACLName),
+				zap.String("resourceType", string(resourceType.ResourceType)),
+			)
+			continue
+		}
+
+		for _, arn := range resourcesOutput.ResourceArns {
+			resources = append(resources, wafResource{
+				ARN:       arn,
+				Dimension: resourceType.Dimension,
+				Label:     resourceType.Label,
+			})
+		}
+	}
+
+	return resources
+}
+
+// Helper function to shorten a resource ARN to its resource part, e.g.
+// app/name/id for an ALB or restapis/id/stages/name for API Gateway
+func wafResourceName(arn string) string {
+	name := arn
+	if idx := strings.LastIndex(name, ":"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = strings.TrimPrefix(name, "loadbalancer/")
+	return strings.TrimPrefix(name, "/")
+}
+
+// WAFMetrics reports the web ACL totals, each rule (the Rule dimension) and
+// each associated regional resource (ALB, API Gateway, AppSync, Cognito, App
+// Runner, Verified Access). CLOUDFRONT scoped web ACLs must be queried with
+// us-east-1 clients and have no Region dimension. Daily reports also include
+// the top blocked IPs, countries, URIs and rules from sampled requests.
+func WAFMetrics(ctx context.Context, wafClient *wafv2.Client, cwClient *cloudwatch.Client, webACLId, webACLName, scope, region string, timeParams map[string]time.Time, isDailyReport bool, topN int) (*utils.WAFWebACL, error) {
+	wafScope := wafTypes.ScopeRegional
+	if scope == string(wafTypes.ScopeCloudfront) {
+		wafScope = wafTypes.ScopeCloudfront
+	}
+
+	webACLOutput, err := wafClient.GetWebACL(ctx, &wafv2.GetWebACLInput{
 		Name:  aws.String(webACLName),
-		Scope: wafTypes.ScopeRegional,
+		Scope: wafScope,
 		Id:    aws.String(webACLId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get WAF details: %w", err)
 	}
 
-	webACL, err := wafClient.GetWebACL(ctx, webACLInput)
-	if err != nil {
-		return "", fmt.Errorf("failed to get WAF details: %w", err)
+	webACL := webACLOutput.WebACL
+	webACLMetricName := webACLName
+	if webACL.VisibilityConfig != nil && webACL.VisibilityConfig.MetricName != nil {
+		webACLMetricName = *webACL.VisibilityConfig.MetricName
 	}
 
-	resourcesInput := &wafv2.ListResourcesForWebACLInput{
-		WebACLArn:    webACL.WebACL.ARN,
-		ResourceType: wafTypes.ResourceTypeApplicationLoadBalancer,
+	report := &utils.WAFWebACL{
+		Name:  webACLName,
+		Scope: string(wafScope),
 	}
 
-	resourcesOutput, err := wafClient.ListResourcesForWebACL(ctx, resourcesInput)
-	if err != nil {
-		return "", fmt.Errorf("failed to get resources for WAF: %w", err)
+	ruleDimensions := func(rule string) []types.Dimension {
+		dimensions := []types.Dimension{
+			{
+				Name:  aws.String("WebACL"),
+				Value: aws.String(webACLMetricName),
+			},
+			{
+				Name:  aws.String("Rule"),
+				Value: aws.String(rule),
+			},
+		}
+		if wafScope == wafTypes.ScopeRegional {
+			dimensions = append(dimensions, types.Dimension{
+				Name:  aws.String("Region"),
+				Value: aws.String(region),
+			})
+		}
+		return dimensions
+	}
+
+	report.Metrics = getWAFRequestMetrics(ctx, cwClient, ruleDimensions("ALL"), webACLName, timeParams)
+
+	for _, rule := range webACL.Rules {
+		if rule.VisibilityConfig == nil || !rule.VisibilityConfig.CloudWatchMetricsEnabled || rule.VisibilityConfig.MetricName == nil {
+			continue
+		}
+		report.Rules = append(report.Rules, utils.WAFMetricSet{
+			Name:    aws.ToString(rule.Name),
+			Metrics: getWAFRequestMetrics(ctx, cwClient, ruleDimensions(*rule.VisibilityConfig.MetricName), webACLName, timeParams),
+		})
 	}
 
-	if len(resourcesOutput.ResourceArns) == 0 {
-		return "", fmt.Errorf("no ALB resources associated with WAF")
+	// Per-resource metrics are only published for regional resources
+	if wafScope == wafTypes.ScopeRegional {
+		for _, resource := range getWAFResources(ctx, wafClient, webACL.ARN, webACLName) {
+			dimensions := []types.Dimension{
+				{
+					Name:  aws.String("Resource"),
+					Value: aws.String(resource.ARN),
+				},
+				{
+					Name:  aws.String("ResourceType"),
+					Value: aws.String(resource.Dimension),
+				},
+			}
+			report.Resources = append(report.Resources, utils.WAFMetricSet{
+				Name:    resource.Label + " " + wafResourceName(resource.ARN),
+				Metrics: getWAFRequestMetrics(ctx, cwClient, dimensions, webACLName, timeParams),
+			})
+		}
 	}
 
-	if len(resourcesOutput.ResourceArns) > 1 {
-		return "", fmt.Errorf("multiple ALB resources found, expected only one")
+	// A negative topN disables sampled requests
+	if isDailyReport && topN > 0 {
+		getWAFBlockedSamples(ctx, wafClient, webACL, wafScope, webACLMetricName, report, timeParams, topN)
 	}
 
-	return resourcesOutput.ResourceArns[0], nil
+	return report, nil
 }
 
-func WAFMetrics(ctx context.Context, wafClient *wafv2.Client, cwClient *cloudwatch.Client, webACLId, webACLName string, timeParams map[string]time.Time) (map[string]float64, error) {
-	albARN, err := getALBARNFromWAF(ctx, wafClient, webACLName, webACLId)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ALB ARN from WAF: %w", err)
+// Helper function to aggregate the blocked sampled requests of every rule.
+// WAF only keeps samples for the last 3 hours, so the window is capped.
+// Each sample is weighted by the number of requests it represents.
+func getWAFBlockedSamples(ctx context.Context, wafClient *wafv2.Client, webACL *wafTypes.WebACL, wafScope wafTypes.Scope, webACLMetricName string, report *utils.WAFWebACL, timeParams map[string]time.Time, topN int) {
+	startTime := timeParams["startTime"]
+	if minStart := timeParams["endTime"].Add(-3 * time.Hour); startTime.Before(minStart) {
+		startTime = minStart
+	}
+
+	type sampleSource struct {
+		RuleName   string
+		MetricName string
+	}
+
+	var sources []sampleSource
+	for _, rule := range webACL.Rules {
+		if rule.VisibilityConfig == nil || !rule.VisibilityConfig.SampledRequestsEnabled || rule.VisibilityConfig.MetricName == nil {
+			continue
+		}
+		sources = append(sources, sampleSource{aws.ToString(rule.Name), *rule.VisibilityConfig.MetricName})
+	}
+	// Requests blocked by the default action are sampled under the web ACL metric name
+	if webACL.DefaultAction != nil && webACL.DefaultAction.Block != nil {
+		sources = append(sources, sampleSource{"Default_Action", webACLMetricName})
+	}
+
+	ips := map[string]int64{}
+	countries := map[string]int64{}
+	uris := map[string]int64{}
+	rules := map[string]int64{}
+
+	for _, source := range sources {
+		output, err := wafClient.GetSampledRequests(ctx, &wafv2.GetSampledRequestsInput{
+			WebAclArn:      webACL.ARN,
+			RuleMetricName: aws.String(source.MetricName),
+			Scope:          wafScope,
+			TimeWindow: &wafTypes.TimeWindow{
+				StartTime: aws.Time(startTime),
+				EndTime:   aws.Time(timeParams["endTime"]),
+			},
+			MaxItems: aws.Int64(500),
+		})
+		if err != nil {
+			utils.Logger.Error("Failed to get WAF sampled requests",
+				zap.Error(err),
+				zap.String("ruleMetricName", source.MetricName),
+				zap.String("webACLName", report.Name),
+			)
+			continue
+		}
+
+		for _, sample := range output.SampledRequests {
+			if aws.ToString(sample.Action) != "BLOCK" || sample.Request == nil {
+				continue
+			}
+
+			ruleName := source.RuleName
+			if sample.RuleNameWithinRuleGroup != nil {
+				ruleName = *sample.RuleNameWithinRuleGroup
+			}
+
+			ips[aws.ToString(sample.Request.ClientIP)] += sample.Weight
+			countries[aws.ToString(sample.Request.Country)] += sample.Weight
+			uris[aws.ToString(sample.Request.URI)] += sample.Weight
+			rules[ruleName] += sample.Weight
+		}
+	}
+
+	report.TopBlockedIPs = topCounts(ips, topN)
+	report.TopBlockedCountries = topCounts(countries, topN)
+	report.TopBlockedURIs = topCounts(uris, topN)
+	report.TopBlockingRules = topCounts(rules, topN)
+}
+
+// Helper function to sort counts descending and keep the first n
+func topCounts(counts map[string]int64, n int) []utils.CountItem {
+	items := make([]utils.CountItem, 0, len(counts))
+	for name, count := range counts {
+		if name == "" {
+			continue
+		}
+		items = append(items, utils.CountItem{Name: name, Count: count})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	if len(items) > n {
+		items = items[:n]
 	}
+	return items
+}
 
+func getWAFRequestMetrics(ctx context.Context, cwClient *cloudwatch.Client, dimensions []types.Dimension, webACLName string, timeParams map[string]time.Time) map[string]float64 {
 	metrics := map[string]float64{}
 	period := aws.Int32(3600)
 	if timeParams["endTime"].Sub(timeParams["startTime"]) >= 24*time.Hour {
@@ -66,22 +280,14 @@ func WAFMetrics(ctx context.Context, wafClient *wafv2.Client, cwClient *cloudwat
 	}{
 		{"AllowedRequests", "Sum"},
 		{"BlockedRequests", "Sum"},
+		{"CountedRequests", "Sum"},
 	}
 
 	for _, metric := range wafMetrics {
 		input := &cloudwatch.GetMetricStatisticsInput{
 			Namespace:  aws.String("AWS/WAFV2"),
 			MetricName: aws.String(metric.Name),
-			Dimensions: []types.Dimension{
-				{
-					Name:  aws.String("Resource"),
-					Value: aws.String(albARN),
-				},
-				{
-					Name:  aws.String("ResourceType"),
-					Value: aws.String("ALB"),
-				},
-			},
+			Dimensions: dimensions,
 			StartTime:  aws.Time(timeParams["startTime"]),
 			EndTime:    aws.Time(timeParams["endTime"]),
 			Period:     period,
@@ -94,8 +300,7 @@ func WAFMetrics(ctx context.Context, wafClient *wafv2.Client, cwClient *cloudwat
 				zap.Error(err),
 				zap.String("metricName", metric.Name),
 				zap.String("statistic", metric.Statistic),
-				zap.String("webACLId", webACLId),
-				zap.String("albARN", albARN),
+				zap.String("webACLName", webACLName),
 				zap.Int32("period", *period),
 			)
 			continue // Continue with other metrics if one fails
@@ -108,5 +313,5 @@ func WAFMetrics(ctx context.Context, wafClient *wafv2.Client, cwClient *cloudwat
 		}
 	}
 
-	return metrics, nil
+	return metrics
 }
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"reflect"
+	"telegraws/utils"
+	"testing"
+)
+
+func TestWAFResourceName(t *testing.T) {
+	tests := []struct {
+		arn  string
+		want string
+	}{
+		{"arn:aws:elasticloadbalancing:eu-west-1:123456789012:loadbalancer/app/web/50dc6c495c0c9188", "app/web/50dc6c495c0c9188"},
+		{"arn:aws:apigateway:eu-west-1::/restapis/a1b2c3/stages/prod", "restapis/a1b2c3/stages/prod"},
+		{"arn:aws:appsync:eu-west-1:123456789012:apis/abcdefghij", "apis/abcdefghij"},
+		{"arn:aws:cognito-idp:eu-west-1:123456789012:userpool/eu-west-1_AbCdEf", "userpool/eu-west-1_AbCdEf"},
+		{"name-only", "name-only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := wafResourceName(tt.arn); got != tt.want {
+				t.Errorf("wafResourceName(%q) = %q, want %q", tt.arn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int64
+		n      int
+		want   []utils.CountItem
+	}{
+		{
+			name:   "sorted by count then name",
+			counts: map[string]int64{"b": 2, "a": 2, "c": 5, "d": 1},
+			n:      10,
+			want:   []utils.CountItem{{Name: "c", Count: 5}, {Name: "a", Count: 2}, {Name: "b", Count: 2}, {Name: "d", Count: 1}},
+		},
+		{
+			name:   "limited to n",
+			counts: map[string]int64{"a": 1, "b": 2, "c": 3},
+			n:      2,
+			want:   []utils.CountItem{{Name: "c", Count: 3}, {Name: "b", Count: 2}},
+		},
+		{
+			name:   "empty names skipped",
+			counts: map[string]int64{"": 9, "a": 1},
+			n:      5,
+			want:   []utils.CountItem{{Name: "a", Count: 1}},
+		},
+		{
+			name:   "no counts",
+			counts: map[string]int64{},
+			n:      5,
+			want:   []utils.CountItem{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topCounts(tt.counts, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("topCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,25 +3,44 @@ package utils
 import (
 	"fmt"
 	"html"
+	"sort"
 	"strings"
 	"telegraws/config"
 )
 
-func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics map[string]any, forEmail bool) string {
+// Longest list of RDS events, alarms or unattached volumes a section shows,
+// keeping the report under the Telegram message limit
+const maxListItems = 10
+
+type renderer struct {
+	bold func(string) string
+	esc  func(string) string
+	nl   string
+	sep  func(daily bool) string
+	pre  func(string) string
+}
+
+// Helper function to get the Telegram Markdown or email HTML renderer
+func newRenderer(forEmail bool) renderer {
+	if forEmail {
+		return renderer{
+			bold: func(s string) string { return "<strong>" + html.EscapeString(s) + "</strong>" },
+			esc:  html.EscapeString,
+			nl:   "<br>",
+			sep:  func(_ bool) string { return `<hr style="border:none;border-top:1px solid #ccc;margin:12px 0;">` },
+			pre:  func(s string) string { return "<pre>" + html.EscapeString(s) + "</pre>" },
+		}
+	}
+
 	escapeMarkdown := func(text string) string {
 		text = strings.ReplaceAll(text, "_", "\\_")
 		text = strings.ReplaceAll(text, "*", "\\*")
+		text = strings.ReplaceAll(text, "`", "\\`")
+		text = strings.ReplaceAll(text, "[", "\\[")
 		return text
 	}
 
-	type renderer struct {
-		bold func(string) string
-		esc  func(string) string
-		nl   string
-		sep  func(daily bool) string
-	}
-
-	tg := renderer{
+	return renderer{
 		bold: func(s string) string { return "*" + s + "*" },
 		esc:  escapeMarkdown,
 		nl:   "\n",
@@ -31,19 +50,12 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 			}
 			return "\n- - - - - - - - - - - - - - -\n\n"
 		},
+		pre: func(s string) string { return "```\n" + s + "```\n" },
 	}
+}
 
-	htmlR := renderer{
-		bold: func(s string) string { return "<strong>" + html.EscapeString(s) + "</strong>" },
-		esc:  html.EscapeString,
-		nl:   "<br>",
-		sep:  func(_ bool) string { return `<hr style="border:none;border-top:1px solid #ccc;margin:12px 0;">` },
-	}
-
-	r := tg
-	if forEmail {
-		r = htmlR
-	}
+func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics map[string]any, forEmail bool) string {
+	r := newRenderer(forEmail)
 
 	var b strings.Builder
 
@@ -51,8 +63,295 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	b.WriteString(r.sep(timeParams.IsDailyReport))
 	b.WriteString(timeParams.EndTime.Format("02/01/2006 15:04:05"))
 	b.WriteString(r.nl)
+
+	var budgetsReport *BudgetsReport
+	if cfg.Services.Budgets.Enabled {
+		if d, ok := allMetrics["budgets"]; ok {
+			budgetsReport = d.(*BudgetsReport)
+		}
+	}
+
+	// Budgets over the warning percent are highlighted in the header
+	if budgetsReport != nil {
+		for _, budget := range budgetsReport.Budgets {
+			if budget.ActualPercent > cfg.Services.Budgets.WarningPercent {
+				b.WriteString(fmt.Sprintf("%s: %s at %.0f%%%s", r.bold("BUDGET ALERT"), r.esc(budget.Name), budget.ActualPercent, r.nl))
+			} else if budget.ForecastPercent > cfg.Services.Budgets.WarningPercent {
+				b.WriteString(fmt.Sprintf("%s: %s forecast at %.0f%%%s", r.bold("BUDGET ALERT"), r.esc(budget.Name), budget.ForecastPercent, r.nl))
+			}
+		}
+	}
 	b.WriteString(r.nl)
 
+	// AWS Health
+	if cfg.Services.Health.Enabled {
+		if d, ok := allMetrics["health"]; ok {
+			events := d.([]HealthEvent)
+			if len(events) > 0 {
+				b.WriteString(r.bold("AWS HEALTH") + r.nl)
+				for _, event := range events {
+					when := "since " + event.StartTime.Format("02/01 15:04")
+					if event.Status == "upcoming" || event.StartTime.After(timeParams.EndTime) {
+						when = "starts " + event.StartTime.Format("02/01 15:04")
+					}
+					if !event.EndTime.IsZero() {
+						when += ", ends " + event.EndTime.Format("02/01 15:04")
+					}
+					b.WriteString(fmt.Sprintf("%s %s (%s, %s)%s",
+						r.esc(strings.ToUpper(event.Status)), r.esc(event.TypeCode), r.esc(event.Region), when, r.nl))
+					for _, entity := range event.Entities {
+						b.WriteString("- " + r.esc(entity) + r.nl)
+					}
+					if hidden := event.EntityCount - len(event.Entities); hidden > 0 {
+						b.WriteString(fmt.Sprintf("- and %d more%s", hidden, r.nl))
+					}
+				}
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// Certificates, only CRITICAL ones outside daily reports
+	if cfg.Services.Certificates.Enabled {
+		if d, ok := allMetrics["certificates"]; ok {
+			var certificates []Certificate
+			for _, certificate := range d.([]Certificate) {
+				if timeParams.IsDailyReport || certificate.Severity == "CRITICAL" {
+					certificates = append(certificates, certificate)
+				}
+			}
+
+			if len(certificates) > 0 {
+				b.WriteString(r.bold("CERTIFICATES") + r.nl)
+				for _, certificate := range certificates {
+					expiry := fmt.Sprintf("expires in %d days", certificate.DaysLeft)
+					switch {
+					case certificate.DaysLeft < 0:
+						expiry = "expired"
+					case certificate.DaysLeft == 0:
+						expiry = "expires today"
+					}
+					b.WriteString(fmt.Sprintf("%s %s (%s %s, %s) %s, %s%s",
+						certificate.Severity, r.esc(certificate.DomainName), certificate.Source, r.esc(certificate.Region),
+						r.esc(strings.ToLower(certificate.Type)), expiry, certificate.NotAfter.Format("02/01/2006"), r.nl))
+					if certificate.RenewalEligibility == "INELIGIBLE" {
+						b.WriteString("Not eligible for automatic renewal" + r.nl)
+					}
+					for _, resource := range certificate.InUseBy {
+						b.WriteString("- " + r.esc(resource) + r.nl)
+					}
+				}
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// Security findings, only HIGH and CRITICAL ones outside daily reports
+	if cfg.Services.Security.Enabled {
+		if d, ok := allMetrics["security"]; ok {
+			var findings []SecurityFinding
+			counts := map[string]map[string]int{}
+			for _, finding := range d.([]SecurityFinding) {
+				if !timeParams.IsDailyReport && severityRank(finding.Severity) > severityRank("HIGH") {
+					continue
+				}
+				findings = append(findings, finding)
+				if counts[finding.Source] == nil {
+					counts[finding.Source] = map[string]int{}
+				}
+				counts[finding.Source][finding.Severity]++
+			}
+
+			if len(findings) > 0 {
+				sort.SliceStable(findings, func(i, j int) bool {
+					return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+				})
+
+				b.WriteString(r.bold("SECURITY") + r.nl)
+				for _, source := range []string{"GuardDuty", "SecurityHub"} {
+					sourceCounts, ok := counts[source]
+					if !ok {
+						continue
+					}
+					var parts []string
+					for _, severity := range []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL"} {
+						if sourceCounts[severity] > 0 {
+							parts = append(parts, fmt.Sprintf("%d %s", sourceCounts[severity], strings.ToLower(severity)))
+						}
+					}
+					b.WriteString(fmt.Sprintf("%s: %s%s", source, strings.Join(parts, ", "), r.nl))
+				}
+
+				if len(findings) > cfg.Services.Security.TopN {
+					findings = findings[:cfg.Services.Security.TopN]
+				}
+				for _, finding := range findings {
+					b.WriteString(fmt.Sprintf("%s %s%s", finding.Severity, r.esc(truncate(finding.Title, 120)), r.nl))
+					b.WriteString(fmt.Sprintf("- %s, %s%s", r.esc(finding.Type), r.esc(finding.Resource), r.nl))
+				}
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// CloudTrail
+	if cfg.Services.CloudTrail.Enabled {
+		if d, ok := allMetrics["cloudtrail"]; ok {
+			events := d.([]CloudTrailEvent)
+			if len(events) > 0 {
+				b.WriteString(r.bold("CLOUDTRAIL") + r.nl)
+				shown := events
+				if len(shown) > cfg.Services.CloudTrail.MaxEvents {
+					shown = shown[:cfg.Services.CloudTrail.MaxEvents]
+				}
+				for _, event := range shown {
+					what := event.EventName
+					if event.Target != "" {
+						what += " " + event.Target
+					}
+					var flags []string
+					if event.Root {
+						flags = append(flags, "ROOT")
+					}
+					if event.Failed {
+						flags = append(flags, "FAILED")
+					}
+					if len(flags) > 0 {
+						what += " [" + strings.Join(flags, ", ") + "]"
+					}
+
+					// Keep only the user or role session name of the ARN
+					who := event.User
+					if idx := strings.Index(who, "/"); idx >= 0 {
+						who = who[idx+1:]
+					}
+					b.WriteString(fmt.Sprintf("%s %s: %s (%s)%s",
+						event.Time.UTC().Format("15:04"), r.esc(who), r.esc(what), r.esc(event.SourceIP), r.nl))
+				}
+				if hidden := len(events) - len(shown); hidden > 0 {
+					b.WriteString(fmt.Sprintf("and %d more%s", hidden, r.nl))
+				}
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// Service Quotas
+	if cfg.Services.Quotas.Enabled {
+		if d, ok := allMetrics["quotas"]; ok {
+			quotas := d.([]*QuotaUsage)
+			b.WriteString(r.bold("QUOTAS") + r.nl)
+			var rows [][]string
+			for _, quota := range quotas {
+				rows = append(rows, []string{
+					truncate(quota.QuotaName, 28),
+					fmt.Sprintf("%.0f/%.0f", quota.Usage, quota.Value),
+					fmt.Sprintf("%.1f%%", quota.UsedPercent),
+				})
+			}
+			b.WriteString(r.pre(table([]string{"QUOTA", "USED", "%"}, rows)))
+			for _, quota := range quotas {
+				if quota.UsedPercent > cfg.Services.Quotas.ThresholdPercent {
+					b.WriteString(fmt.Sprintf("%s: %s at %.1f%%%s",
+						r.bold("QUOTA ALERT"), r.esc(quota.QuotaName), quota.UsedPercent, r.nl))
+				}
+			}
+			b.WriteString(r.nl)
+		}
+	}
+
+	// CloudWatch Alarms
+	if cfg.Services.Alarms.Enabled {
+		if d, ok := allMetrics["alarms"]; ok {
+			alarms := d.(*AlarmsReport)
+			b.WriteString(r.bold("ALARMS") + r.nl)
+			b.WriteString(fmt.Sprintf("Not OK: %d of %d%s", len(alarms.Alarms), alarms.Total, r.nl))
+			shown := alarms.Alarms
+			if len(shown) > maxListItems {
+				shown = shown[:maxListItems]
+			}
+			for _, alarm := range shown {
+				b.WriteString(fmt.Sprintf("%s %s (since %s)%s",
+					r.esc(alarm.State), r.esc(truncate(alarm.Name, 80)), alarm.Since.UTC().Format("02/01 15:04"), r.nl))
+				if alarm.Reason != "" {
+					b.WriteString(r.esc(truncate(alarm.Reason, 100)) + r.nl)
+				}
+			}
+			if hidden := len(alarms.Alarms) - len(shown); hidden > 0 {
+				b.WriteString(fmt.Sprintf("and %d more%s", hidden, r.nl))
+			}
+			b.WriteString(fmt.Sprintf("Transitions: %d%s", alarms.Transitions, r.nl))
+			for _, item := range alarms.TopTransitions {
+				b.WriteString(fmt.Sprintf("- %s: %d%s", r.esc(item.Name), item.Count, r.nl))
+			}
+			b.WriteString(r.nl)
+		}
+	}
+
+	// Cost (daily)
+	if cfg.Services.Cost.Enabled && timeParams.IsDailyReport {
+		if d, ok := allMetrics["cost"]; ok {
+			cost := d.(*CostReport)
+			b.WriteString(r.bold("COST") + r.nl)
+			b.WriteString(fmt.Sprintf("Yesterday: %.2f %s%s", cost.Yesterday, cost.Currency, r.nl))
+
+			change := ""
+			if cost.PreviousMonthToDate > 0 {
+				change = fmt.Sprintf(" (%+.1f%% vs last month)", (cost.MonthToDate-cost.PreviousMonthToDate)/cost.PreviousMonthToDate*100)
+			}
+			b.WriteString(fmt.Sprintf("Month to date: %.2f %s%s%s", cost.MonthToDate, cost.Currency, change, r.nl))
+			if cost.HasForecast {
+				b.WriteString(fmt.Sprintf("Forecast: %.2f %s%s", cost.Forecast, cost.Currency, r.nl))
+			}
+
+			if len(cost.Top) > 0 {
+				var rows [][]string
+				for _, item := range cost.Top {
+					rows = append(rows, []string{truncate(item.Name, 28), fmt.Sprintf("%.2f", item.Amount)})
+				}
+				b.WriteString(r.pre(table([]string{strings.ToUpper(cost.GroupBy), "MTD"}, rows)))
+			}
+			b.WriteString(r.nl)
+		}
+	}
+
+	// Budgets
+	if budgetsReport != nil && (len(budgetsReport.Budgets) > 0 || len(budgetsReport.Anomalies) > 0) {
+		b.WriteString(r.bold("BUDGETS") + r.nl)
+		if len(budgetsReport.Budgets) > 0 {
+			var rows [][]string
+			for _, budget := range budgetsReport.Budgets {
+				rows = append(rows, []string{
+					truncate(budget.Name, 20),
+					fmt.Sprintf("%.0f%%", budget.ActualPercent),
+					fmt.Sprintf("%.0f%%", budget.ForecastPercent),
+					fmt.Sprintf("%.0f %s", budget.Limit, budget.Unit),
+				})
+			}
+			b.WriteString(r.pre(table([]string{"BUDGET", "ACTUAL", "FORECAST", "LIMIT"}, rows)))
+		}
+
+		if len(budgetsReport.Anomalies) > 0 {
+			b.WriteString(fmt.Sprintf("Cost Anomalies (last %d days):%s", cfg.Services.Budgets.AnomalyDays, r.nl))
+			for _, anomaly := range budgetsReport.Anomalies {
+				status := "ongoing"
+				if anomaly.EndDate != "" {
+					status = "until " + truncate(anomaly.EndDate, 10)
+				}
+				cause := anomaly.Service
+				if anomaly.Region != "" {
+					cause += " / " + anomaly.Region
+				}
+				if anomaly.UsageType != "" {
+					cause += " / " + anomaly.UsageType
+				}
+				b.WriteString(fmt.Sprintf("+%.2f (%.0f%%) %s, since %s, %s%s",
+					anomaly.Impact, anomaly.ImpactPercent, r.esc(cause), truncate(anomaly.StartDate, 10), status, r.nl))
+			}
+		}
+		b.WriteString(r.nl)
+	}
+
 	// EC2
 	if cfg.Services.EC2.Enabled {
 		if d, ok := allMetrics["ec2"]; ok {
@@ -70,10 +369,203 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	// CloudWatch Agent
 	if cfg.Services.CloudWatchAgent.Enabled {
 		if d, ok := allMetrics["cloudwatchAgent"]; ok {
-			m := d.(map[string]float64)
-			b.WriteString(fmt.Sprintf("Memory: %.2f%% (avg), %.2f%% (max)%s",
-				m["mem_used_percent_Average"], m["mem_used_percent_Maximum"], r.nl))
-			b.WriteString(fmt.Sprintf("Disk: %.2f%%%s", m["disk_used_percent"], r.nl))
+			instances := d.([]*CWAgentInstance)
+
+			// The EC2 instance continues the EC2 block, so render it first without a header
+			sort.SliceStable(instances, func(i, j int) bool {
+				return cfg.Services.EC2.Enabled && instances[i].InstanceID == cfg.Services.EC2.InstanceID &&
+					instances[j].InstanceID != cfg.Services.EC2.InstanceID
+			})
+
+			for _, instance := range instances {
+				m := instance.Metrics
+				if !cfg.Services.EC2.Enabled || instance.InstanceID != cfg.Services.EC2.InstanceID {
+					b.WriteString(fmt.Sprintf("%s: %s%s", r.bold("CW Agent"), r.esc(instance.InstanceID), r.nl))
+				}
+				b.WriteString(fmt.Sprintf("Memory: %.2f%% (avg), %.2f%% (max)%s",
+					m["mem_used_percent_Average"], m["mem_used_percent_Maximum"], r.nl))
+				b.WriteString(fmt.Sprintf("Swap: %.2f%% (avg), %.2f%% (max)%s",
+					m["swap_used_percent_Average"], m["swap_used_percent_Maximum"], r.nl))
+
+				if len(instance.Disks) > 0 {
+					rows := make([][]string, 0, len(instance.Disks))
+					for _, disk := range instance.Disks {
+						inodes := "-"
+						if disk.InodesTotal > 0 {
+							inodes = fmt.Sprintf("%.1f%%", disk.InodesUsed/disk.InodesTotal*100.0)
+						}
+						rows = append(rows, []string{disk.Path, fmt.Sprintf("%.1f%%", disk.UsedPercent), inodes})
+					}
+					b.WriteString(r.pre(table([]string{"PATH", "USED", "INODES"}, rows)))
+				}
+
+				if len(instance.Processes) > 0 {
+					rows := make([][]string, 0, len(instance.Processes))
+					for _, process := range instance.Processes {
+						if !process.Found {
+							rows = append(rows, []string{process.Name, "-", "-", "-"})
+							continue
+						}
+						rows = append(rows, []string{
+							process.Name,
+							fmt.Sprintf("%.0f", process.PIDCount),
+							fmt.Sprintf("%.1f", process.CPUPercent),
+							fmt.Sprintf("%.1f", process.MemoryMB),
+						})
+					}
+					b.WriteString(r.pre(table([]string{"PROCESS", "PIDS", "CPU%", "MEM MB"}, rows)))
+				}
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// EBS
+	if cfg.Services.EBS.Enabled {
+		if d, ok := allMetrics["ebs"]; ok {
+			ebsReport := d.(*EBSReport)
+			showUnattached := timeParams.IsDailyReport && len(ebsReport.Unattached) > 0
+
+			if len(ebsReport.Volumes) > 0 || showUnattached {
+				b.WriteString(r.bold("EBS") + r.nl)
+			}
+
+			if len(ebsReport.Volumes) > 0 {
+				var rows [][]string
+				for _, volume := range ebsReport.Volumes {
+					burst := "-"
+					if value, ok := volume.Metrics["BurstBalance"]; ok {
+						burst = fmt.Sprintf("%.0f%%", value)
+					}
+					rows = append(rows, []string{
+						truncate(volume.InstanceID+" "+volume.Device, 28),
+						fmt.Sprintf("%.0f/%.0f", volume.Metrics["VolumeReadOps"], volume.Metrics["VolumeWriteOps"]),
+						fmt.Sprintf("%.0f/%.0f", volume.Metrics["VolumeReadBytes"], volume.Metrics["VolumeWriteBytes"]),
+						fmt.Sprintf("%.2f", volume.Metrics["VolumeQueueLength"]),
+						burst,
+					})
+				}
+				b.WriteString(r.pre(table([]string{"VOLUME", "OPS R/W", "MB R/W", "QUEUE", "BURST"}, rows)))
+			}
+
+			if showUnattached {
+				// Volumes are sorted by cost, the table shows the most expensive
+				var total float64
+				var rows [][]string
+				for i, volume := range ebsReport.Unattached {
+					total += volume.MonthlyCost
+					if i >= maxListItems {
+						continue
+					}
+					name := volume.VolumeID
+					if volume.Name != "" {
+						name = volume.Name
+					}
+					rows = append(rows, []string{
+						truncate(name, 24),
+						volume.VolumeType,
+						fmt.Sprintf("%d GB", volume.SizeGB),
+						fmt.Sprintf("$%.2f", volume.MonthlyCost),
+					})
+				}
+				b.WriteString(fmt.Sprintf("Unattached Volumes: %d (~$%.2f/month)%s", len(ebsReport.Unattached), total, r.nl))
+				b.WriteString(r.pre(table([]string{"VOLUME", "TYPE", "SIZE", "COST/MO"}, rows)))
+				if hidden := len(ebsReport.Unattached) - len(rows); hidden > 0 {
+					b.WriteString(fmt.Sprintf("and %d more%s", hidden, r.nl))
+				}
+			}
+
+			if len(ebsReport.Volumes) > 0 || showUnattached {
+				b.WriteString(r.nl)
+			}
+		}
+	}
+
+	// EFS
+	if cfg.Services.EFS.Enabled {
+		if d, ok := allMetrics["efs"]; ok {
+			b.WriteString(r.bold("EFS") + r.nl)
+			var rows [][]string
+			for _, fileSystem := range d.([]*EFSFileSystem) {
+				name := fileSystem.Name
+				if name == "" {
+					name = fileSystem.FileSystemID
+				}
+				ioLimit := "-"
+				if value, ok := fileSystem.Metrics["PercentIOLimit"]; ok {
+					ioLimit = fmt.Sprintf("%.0f%%", value)
+				}
+				burst := "-"
+				if value, ok := fileSystem.Metrics["BurstCreditBalance"]; ok {
+					burst = fmt.Sprintf("%.0f", value)
+				}
+				rows = append(rows, []string{
+					truncate(name, 24),
+					ioLimit,
+					burst,
+					fmt.Sprintf("%.0f", fileSystem.Metrics["ClientConnections"]),
+					fmt.Sprintf("%.0f%%", fileSystem.Metrics["ThroughputUtilization"]),
+					fmt.Sprintf("%.2f", fileSystem.Metrics["StorageBytes"]),
+				})
+			}
+			b.WriteString(r.pre(table([]string{"FILE SYSTEM", "IO%", "BURST GB", "CONN", "PEAK THRPT", "SIZE GB"}, rows)))
+			b.WriteString(r.nl)
+		}
+	}
+
+	// VPC
+	if cfg.Services.VPC.Enabled {
+		if d, ok := allMetrics["natGateways"]; ok {
+			b.WriteString(r.bold("NAT GATEWAYS") + r.nl)
+			var processedGB, processingCost float64
+			var rows [][]string
+			for _, natGateway := range d.([]*NATGateway) {
+				name := natGateway.NATGatewayID
+				if natGateway.Name != "" {
+					name = natGateway.Name
+				}
+				m := natGateway.Metrics
+				processedGB += m["BytesInFromSource"] + m["BytesInFromDestination"]
+				processingCost += natGateway.ProcessingCost
+				rows = append(rows, []string{
+					truncate(name, 24),
+					fmt.Sprintf("%.0f", m["ActiveConnectionCount"]),
+					fmt.Sprintf("%.0f", m["ErrorPortAllocation"]),
+					fmt.Sprintf("%.0f", m["PacketsDropCount"]),
+					fmt.Sprintf("%.2f", m["BytesOutToDestination"]),
+				})
+			}
+			b.WriteString(r.pre(table([]string{"NAT GATEWAY", "CONN", "PORT ERR", "DROPS", "OUT GB"}, rows)))
+			b.WriteString(fmt.Sprintf("Data Processed: %.2f GB (~$%.2f)%s", processedGB, processingCost, r.nl))
+			b.WriteString(r.nl)
+		}
+
+		if d, ok := allMetrics["vpnTunnels"]; ok {
+			b.WriteString(r.bold("VPN") + r.nl)
+			for _, tunnel := range d.([]VPNTunnel) {
+				name := tunnel.VPNConnectionID
+				if tunnel.Name != "" {
+					name = tunnel.Name
+				}
+				line := fmt.Sprintf("%s %s: %s since %s", r.esc(name), r.esc(tunnel.OutsideIP),
+					r.esc(tunnel.Status), tunnel.LastStatusChange.UTC().Format("02/01 15:04"))
+				if tunnel.Status != "UP" && tunnel.StatusMessage != "" {
+					line += " (" + r.esc(tunnel.StatusMessage) + ")"
+				}
+				b.WriteString(line + r.nl)
+			}
+			b.WriteString(r.nl)
+		}
+
+		if d, ok := allMetrics["transitGateways"]; ok {
+			b.WriteString(r.bold("TRANSIT GATEWAYS") + r.nl)
+			for _, transitGateway := range d.([]*TransitGateway) {
+				m := transitGateway.Metrics
+				b.WriteString(fmt.Sprintf("%s In: %.2f GB, Out: %.2f GB%s",
+					r.esc(transitGateway.TransitGatewayID), m["BytesIn"], m["BytesOut"], r.nl))
+				b.WriteString(fmt.Sprintf("Dropped: %.0f blackhole, %.0f no route%s",
+					m["PacketDropCountBlackhole"], m["PacketDropCountNoRoute"], r.nl))
+			}
 			b.WriteString(r.nl)
 		}
 	}
@@ -81,13 +573,33 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	// S3 (daily only)
 	if cfg.Services.S3.Enabled && timeParams.IsDailyReport {
 		if d, ok := allMetrics["s3"]; ok {
-			m := d.(map[string]float64)
-			b.WriteString(fmt.Sprintf("%s %s%s", r.bold("S3"), r.esc(cfg.Services.S3.BucketName), r.nl))
-			b.WriteString(fmt.Sprintf("Size: %.2f MB%s", m["BucketSizeBytes"], r.nl))
-			b.WriteString(fmt.Sprintf("Requests: %.0f%s", m["AllRequests"], r.nl))
-			b.WriteString(fmt.Sprintf("4xx Errors: %.0f%s", m["4xxErrors"], r.nl))
-			b.WriteString(fmt.Sprintf("5xx Errors: %.0f%s", m["5xxErrors"], r.nl))
-			b.WriteString(r.nl)
+			for _, bucket := range d.([]*S3Bucket) {
+				m := bucket.Metrics
+				b.WriteString(fmt.Sprintf("%s %s%s", r.bold("S3"), r.esc(bucket.BucketName), r.nl))
+				sizeGrowth, objectsGrowth := "n/a", "n/a"
+				if previous, ok := m["BucketSizeBytes_Previous"]; ok {
+					sizeGrowth = fmt.Sprintf("%+.2f MB", m["BucketSizeBytes"]-previous)
+				}
+				if previous, ok := m["NumberOfObjects_Previous"]; ok {
+					objectsGrowth = fmt.Sprintf("%+.0f", m["NumberOfObjects"]-previous)
+				}
+				b.WriteString(fmt.Sprintf("Size: %.2f MB (%s day-over-day)%s", m["BucketSizeBytes"], sizeGrowth, r.nl))
+				if len(bucket.StorageClasses) > 1 {
+					storageTypes := make([]string, 0, len(bucket.StorageClasses))
+					for storageType := range bucket.StorageClasses {
+						storageTypes = append(storageTypes, storageType)
+					}
+					sort.Strings(storageTypes)
+					for _, storageType := range storageTypes {
+						b.WriteString(fmt.Sprintf("  %s: %.2f MB%s", r.esc(storageType), bucket.StorageClasses[storageType], r.nl))
+					}
+				}
+				b.WriteString(fmt.Sprintf("Objects: %.0f (%s day-over-day)%s", m["NumberOfObjects"], objectsGrowth, r.nl))
+				b.WriteString(fmt.Sprintf("Requests: %.0f%s", m["AllRequests"], r.nl))
+				b.WriteString(fmt.Sprintf("4xx Errors: %.0f%s", m["4xxErrors"], r.nl))
+				b.WriteString(fmt.Sprintf("5xx Errors: %.0f%s", m["5xxErrors"], r.nl))
+				b.WriteString(r.nl)
+			}
 		}
 	}
 
@@ -111,15 +623,27 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	// CloudFront
 	if cfg.Services.CloudFront.Enabled {
 		if d, ok := allMetrics["cloudfront"]; ok {
-			m := d.(map[string]float64)
-			b.WriteString(fmt.Sprintf("%s %s%s", r.bold("CloudFront"), r.esc(cfg.Services.CloudFront.DistributionID), r.nl))
-			b.WriteString(fmt.Sprintf("Requests: %.0f%s", m["Requests"], r.nl))
-			b.WriteString(fmt.Sprintf("Data Downloaded: %.2f MB%s", m["BytesDownloaded"], r.nl))
-			b.WriteString(fmt.Sprintf("Cache Hit Rate: %.2f%%%s", m["CacheHitRate"], r.nl))
-			b.WriteString(fmt.Sprintf("4xx Error Rate: %.2f%%%s", m["4xxErrorRate"], r.nl))
-			b.WriteString(fmt.Sprintf("5xx Error Rate: %.2f%%%s", m["5xxErrorRate"], r.nl))
-			b.WriteString(fmt.Sprintf("Origin Latency: %.2f ms%s", m["OriginLatency"], r.nl))
-			b.WriteString(r.nl)
+			allDistributions := d.(map[string]any)
+			for _, distributionID := range cfg.CloudFrontDistributionIDs() {
+				if dd, ok := allDistributions[distributionID]; ok {
+					m := dd.(map[string]float64)
+					b.WriteString(fmt.Sprintf("%s %s%s", r.bold("CloudFront"), r.esc(distributionID), r.nl))
+					b.WriteString(fmt.Sprintf("Requests: %.0f%s", m["Requests"], r.nl))
+					b.WriteString(fmt.Sprintf("Data Downloaded: %.2f MB%s", m["BytesDownloaded"], r.nl))
+					b.WriteString(fmt.Sprintf("4xx Error Rate: %.2f%%%s", m["4xxErrorRate"], r.nl))
+					b.WriteString(fmt.Sprintf("5xx Error Rate: %.2f%%%s", m["5xxErrorRate"], r.nl))
+					if cfg.Services.CloudFront.AdditionalMetrics {
+						b.WriteString(fmt.Sprintf("Cache Hit Rate: %.2f%%%s", m["CacheHitRate"], r.nl))
+						b.WriteString(fmt.Sprintf("Origin Latency: %.2f ms%s", m["OriginLatency"], r.nl))
+						b.WriteString(fmt.Sprintf("Total Error Rate: %.2f%%%s", m["TotalErrorRate"], r.nl))
+						b.WriteString(fmt.Sprintf("401: %.2f%%, 403: %.2f%%, 404: %.2f%%%s",
+							m["401ErrorRate"], m["403ErrorRate"], m["404ErrorRate"], r.nl))
+						b.WriteString(fmt.Sprintf("502: %.2f%%, 503: %.2f%%, 504: %.2f%%%s",
+							m["502ErrorRate"], m["503ErrorRate"], m["504ErrorRate"], r.nl))
+					}
+					b.WriteString(r.nl)
+				}
+			}
 		}
 	}
 
@@ -129,68 +653,167 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 			allTables := d.(map[string]any)
 			for _, table := range cfg.Services.DynamoDB.TableNames {
 				if td, ok := allTables[table]; ok {
-					m := td.(map[string]float64)
+					t := td.(*DynamoDBTable)
+					m := t.Metrics
 					b.WriteString(fmt.Sprintf("%s %s%s", r.bold("DynamoDB"), r.esc(table), r.nl))
 					b.WriteString(fmt.Sprintf("Total Requests: %.0f%s", m["RequestCount"], r.nl))
 					b.WriteString(fmt.Sprintf("Read Throttles: %.0f%s", m["ReadThrottledRequests"], r.nl))
 					b.WriteString(fmt.Sprintf("Write Throttles: %.0f%s", m["WriteThrottledRequests"], r.nl))
 					b.WriteString(fmt.Sprintf("Latency: %.2f ms%s", m["SuccessfulRequestLatency"], r.nl))
-					b.WriteString(fmt.Sprintf("Read Capacity: %.0f units%s", m["ConsumedReadCapacityUnits"], r.nl))
-					b.WriteString(fmt.Sprintf("Write Capacity: %.0f units%s", m["ConsumedWriteCapacityUnits"], r.nl))
+					if t.OnDemand {
+						b.WriteString(fmt.Sprintf("Read Capacity: %.0f units (on-demand)%s", m["ConsumedReadCapacityUnits"], r.nl))
+						b.WriteString(fmt.Sprintf("Write Capacity: %.0f units (on-demand)%s", m["ConsumedWriteCapacityUnits"], r.nl))
+					} else {
+						b.WriteString(fmt.Sprintf("Read Capacity: %.0f units%s", m["ConsumedReadCapacityUnits"], r.nl))
+						b.WriteString(fmt.Sprintf("Write Capacity: %.0f units%s", m["ConsumedWriteCapacityUnits"], r.nl))
+						if v, ok := m["ReadUtilization_Average"]; ok {
+							b.WriteString(fmt.Sprintf("Read Utilization: %.2f%% (avg), %.2f%% (peak) of %.0f RCU%s",
+								v, m["ReadUtilization_Peak"], m["ReadCapacityProvisioned"], r.nl))
+						}
+						if v, ok := m["WriteUtilization_Average"]; ok {
+							b.WriteString(fmt.Sprintf("Write Utilization: %.2f%% (avg), %.2f%% (peak) of %.0f WCU%s",
+								v, m["WriteUtilization_Peak"], m["WriteCapacityProvisioned"], r.nl))
+						}
+					}
 					totalErrors := m["UserErrors"] + m["SystemErrors"]
 					b.WriteString(fmt.Sprintf("DB Errors: %.0f%s", totalErrors, r.nl))
+					b.WriteString(fmt.Sprintf("Items: %d, Size: %.2f MB%s", t.ItemCount, float64(t.SizeBytes)/(1024.0*1024.0), r.nl))
+					backupLine := "PITR: disabled"
+					if t.PITREnabled {
+						backupLine = "PITR: enabled"
+					}
+					if !t.LastBackup.IsZero() {
+						backupLine += ", Last Backup: " + t.LastBackup.Format("02/01/2006 15:04")
+					}
+					b.WriteString(backupLine + r.nl)
+					for _, index := range t.Indexes {
+						im := index.Metrics
+						b.WriteString(fmt.Sprintf("GSI %s: %.0f RCU, %.0f WCU, %.0f/%.0f throttles (r/w)%s",
+							r.esc(index.IndexName), im["ConsumedReadCapacityUnits"], im["ConsumedWriteCapacityUnits"],
+							im["ReadThrottleEvents"], im["WriteThrottleEvents"], r.nl))
+						if v, ok := im["ReadUtilization_Peak"]; ok {
+							b.WriteString(fmt.Sprintf("GSI %s Utilization: %.2f%% read, %.2f%% write (peak)%s",
+								r.esc(index.IndexName), v, im["WriteUtilization_Peak"], r.nl))
+						}
+					}
 					b.WriteString(r.nl)
 				}
 			}
 		}
 	}
 
+	// Route 53
+	if cfg.Services.Route53.Enabled {
+		if d, ok := allMetrics["route53"]; ok {
+			healthChecks := d.([]*Route53HealthCheck)
+			b.WriteString(r.bold("ROUTE 53") + r.nl)
+			for _, healthCheck := range healthChecks {
+				name := healthCheck.Name
+				if name == "" {
+					name = healthCheck.Endpoint
+				}
+				if name == "" {
+					name = healthCheck.ID
+				}
+
+				status := "NO DATA"
+				if healthCheck.HasStatus {
+					status = "HEALTHY"
+					if healthCheck.Metrics["HealthCheckStatus_Minimum"] < 1 {
+						status = "UNHEALTHY"
+					}
+				}
+
+				b.WriteString(fmt.Sprintf("%s: %s%s", r.esc(name), status, r.nl))
+				b.WriteString(fmt.Sprintf("Healthy Checkers: %.1f%%%s", healthCheck.Metrics["HealthCheckPercentageHealthy_Average"], r.nl))
+				if healthCheck.MeasureLatency {
+					b.WriteString(fmt.Sprintf("Connection: %.0f ms, TTFB: %.0f ms%s",
+						healthCheck.Metrics["ConnectionTime_Average"], healthCheck.Metrics["TimeToFirstByte_Average"], r.nl))
+				}
+			}
+			b.WriteString(r.nl)
+		}
+	}
+
 	// RDS
 	if cfg.Services.RDS.Enabled {
 		if d, ok := allMetrics["rds"]; ok {
-			m := d.(map[string]float64)
+			report := d.(*RDSReport)
 
-			var header string
-			if cfg.Services.RDS.ClusterID != "" && cfg.Services.RDS.DBInstanceIdentifier != "" {
-				header = fmt.Sprintf("%s %s / %s",
-					r.bold("RDS"), r.esc(cfg.Services.RDS.ClusterID), r.esc(cfg.Services.RDS.DBInstanceIdentifier))
-			} else if cfg.Services.RDS.ClusterID != "" {
-				header = fmt.Sprintf("%s Cluster %s", r.bold("RDS"), r.esc(cfg.Services.RDS.ClusterID))
+			if report.ClusterID != "" {
+				b.WriteString(fmt.Sprintf("%s Cluster %s%s", r.bold("RDS"), r.esc(report.ClusterID), r.nl))
+				if v, ok := report.Cluster["VolumeBytesUsed"]; ok {
+					b.WriteString(fmt.Sprintf("Volume Size: %.2f GB%s", v, r.nl))
+				}
+				if v, ok := report.Cluster["VolumeReadIOPs"]; ok {
+					b.WriteString(fmt.Sprintf("Read IOPS: %.0f%s", v, r.nl))
+				}
+				if v, ok := report.Cluster["VolumeWriteIOPs"]; ok {
+					b.WriteString(fmt.Sprintf("Write IOPS: %.0f%s", v, r.nl))
+				}
 			} else {
-				header = fmt.Sprintf("%s Instance %s", r.bold("RDS"), r.esc(cfg.Services.RDS.DBInstanceIdentifier))
+				b.WriteString(r.bold("RDS") + r.nl)
 			}
-			b.WriteString(header + r.nl)
 
-			if cfg.Services.RDS.DBInstanceIdentifier != "" {
-				if v, ok := m["Instance_CPUUtilization_Average"]; ok {
+			for _, instance := range report.Instances {
+				m := instance.Metrics
+				b.WriteString(r.nl)
+				if instance.Role != "" {
+					b.WriteString(fmt.Sprintf("Instance %s (%s)%s", r.esc(instance.InstanceID), instance.Role, r.nl))
+				} else {
+					b.WriteString(fmt.Sprintf("Instance %s%s", r.esc(instance.InstanceID), r.nl))
+				}
+				if v, ok := m["CPUUtilization_Average"]; ok {
 					line := fmt.Sprintf("CPU: %.2f%% (avg)", v)
-					if v2, ok2 := m["Instance_CPUUtilization_Maximum"]; ok2 {
+					if v2, ok2 := m["CPUUtilization_Maximum"]; ok2 {
 						line += fmt.Sprintf(", %.2f%% (max)", v2)
 					}
 					b.WriteString(line + r.nl)
 				}
-				if v, ok := m["Instance_FreeableMemory"]; ok {
+				if v, ok := m["FreeableMemory"]; ok {
 					b.WriteString(fmt.Sprintf("Free Memory: %.2f GB%s", v, r.nl))
 				}
-				if v, ok := m["Instance_DatabaseConnections"]; ok {
+				if v, ok := m["DatabaseConnections"]; ok {
 					b.WriteString(fmt.Sprintf("Connections: %.0f%s", v, r.nl))
 				}
-				if v, ok := m["Instance_ReadLatency"]; ok {
+				if v, ok := m["ReadLatency"]; ok {
 					b.WriteString(fmt.Sprintf("Read Latency: %.2f ms%s", v, r.nl))
 				}
-				if v, ok := m["Instance_WriteLatency"]; ok {
+				if v, ok := m["WriteLatency"]; ok {
 					b.WriteString(fmt.Sprintf("Write Latency: %.2f ms%s", v, r.nl))
 				}
-			}
-			if cfg.Services.RDS.ClusterID != "" {
-				if v, ok := m["Cluster_VolumeBytesUsed"]; ok {
-					b.WriteString(fmt.Sprintf("Volume Size: %.2f GB%s", v, r.nl))
+				if v, ok := m["AuroraReplicaLag"]; ok {
+					b.WriteString(fmt.Sprintf("Replica Lag: %.2f ms (max)%s", v, r.nl))
 				}
-				if v, ok := m["Cluster_VolumeReadIOPs"]; ok {
-					b.WriteString(fmt.Sprintf("Read IOPS: %.0f%s", v, r.nl))
+				if v, ok := m["ServerlessDatabaseCapacity"]; ok {
+					b.WriteString(fmt.Sprintf("Capacity: %.2f ACU (%.2f%% utilization)%s", v, m["ACUUtilization"], r.nl))
 				}
-				if v, ok := m["Cluster_VolumeWriteIOPs"]; ok {
-					b.WriteString(fmt.Sprintf("Write IOPS: %.0f%s", v, r.nl))
+				if len(instance.TopSQL) > 0 {
+					b.WriteString("Top SQL (avg load):" + r.nl)
+					for _, item := range instance.TopSQL {
+						b.WriteString(fmt.Sprintf("%.2f %s%s", item.Load, r.esc(truncate(item.Name, 80)), r.nl))
+					}
+				}
+				if len(instance.TopWaits) > 0 {
+					b.WriteString("Top Waits (avg load):" + r.nl)
+					for _, item := range instance.TopWaits {
+						b.WriteString(fmt.Sprintf("%.2f %s%s", item.Load, r.esc(item.Name), r.nl))
+					}
+				}
+			}
+
+			if len(report.Events) > 0 {
+				b.WriteString(r.nl)
+				b.WriteString(fmt.Sprintf("Events: %d%s", len(report.Events), r.nl))
+				// Events are oldest first, show the most recent ones
+				shown := report.Events
+				if len(shown) > maxListItems {
+					shown = shown[len(shown)-maxListItems:]
+					b.WriteString(fmt.Sprintf("%d earlier events not shown%s", len(report.Events)-len(shown), r.nl))
+				}
+				for _, event := range shown {
+					b.WriteString(fmt.Sprintf("%s %s: %s%s",
+						event.Time.Format("02/01 15:04"), r.esc(event.SourceID), r.esc(truncate(event.Message, 120)), r.nl))
 				}
 			}
 			b.WriteString(r.nl)
@@ -200,11 +823,48 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	// WAF
 	if cfg.Services.WAF.Enabled {
 		if d, ok := allMetrics["waf"]; ok {
-			m := d.(map[string]float64)
-			b.WriteString(fmt.Sprintf("%s %s%s", r.bold("WAF"), r.esc(cfg.Services.WAF.WebACLName), r.nl))
-			b.WriteString(fmt.Sprintf("Allowed Requests: %.0f%s", m["AllowedRequests"], r.nl))
-			b.WriteString(fmt.Sprintf("Blocked Requests: %.0f%s", m["BlockedRequests"], r.nl))
-			b.WriteString(r.nl)
+			for _, webACL := range d.([]*WAFWebACL) {
+				m := webACL.Metrics
+				b.WriteString(fmt.Sprintf("%s %s (%s)%s", r.bold("WAF"), r.esc(webACL.Name), webACL.Scope, r.nl))
+				b.WriteString(fmt.Sprintf("Allowed Requests: %.0f%s", m["AllowedRequests"], r.nl))
+				b.WriteString(fmt.Sprintf("Blocked Requests: %.0f%s", m["BlockedRequests"], r.nl))
+				b.WriteString(fmt.Sprintf("Counted Requests: %.0f%s", m["CountedRequests"], r.nl))
+				for _, resource := range webACL.Resources {
+					rm := resource.Metrics
+					b.WriteString(fmt.Sprintf("%s: %.0f allowed, %.0f blocked, %.0f counted%s",
+						r.esc(resource.Name), rm["AllowedRequests"], rm["BlockedRequests"], rm["CountedRequests"], r.nl))
+				}
+				for _, rule := range webACL.Rules {
+					rm := rule.Metrics
+					// Skip rules that did not match anything in the window
+					if rm["AllowedRequests"]+rm["BlockedRequests"]+rm["CountedRequests"] == 0 {
+						continue
+					}
+					b.WriteString(fmt.Sprintf("Rule %s: %.0f allowed, %.0f blocked, %.0f counted%s",
+						r.esc(rule.Name), rm["AllowedRequests"], rm["BlockedRequests"], rm["CountedRequests"], r.nl))
+				}
+				if timeParams.IsDailyReport {
+					samples := []struct {
+						Title string
+						Items []CountItem
+					}{
+						{"Top Blocked IPs", webACL.TopBlockedIPs},
+						{"Top Blocked Countries", webACL.TopBlockedCountries},
+						{"Top Blocked URIs", webACL.TopBlockedURIs},
+						{"Top Blocking Rules", webACL.TopBlockingRules},
+					}
+					for _, sample := range samples {
+						if len(sample.Items) == 0 {
+							continue
+						}
+						b.WriteString(sample.Title + " (sampled, last 3h):" + r.nl)
+						for _, item := range sample.Items {
+							b.WriteString(fmt.Sprintf("  %s: %d%s", r.esc(truncate(item.Name, 60)), item.Count, r.nl))
+						}
+					}
+				}
+				b.WriteString(r.nl)
+			}
 		}
 	}
 
@@ -212,39 +872,60 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	if cfg.Services.CloudWatchLogs.Enabled {
 		if d, ok := allMetrics["cloudwatchLogs"]; ok {
 			logsMetrics := d.(map[string]any)
-			applicationLogs := make(map[string]any)
-			lambdaLogs := make(map[string]any)
+			var applicationLogs, lambdaLogs []config.LogGroupConfig
 
-			for _, name := range cfg.Services.CloudWatchLogs.LogGroupNames {
-				if data, ok := logsMetrics[name]; ok {
-					if strings.Contains(name, "/aws/lambda/") {
-						lambdaLogs[name] = data
-					} else {
-						applicationLogs[name] = data
-					}
+			// Includes the log groups discovered by pattern
+			logGroups := cfg.CloudWatchLogGroups()
+			if g, ok := allMetrics["cloudwatchLogGroups"]; ok {
+				logGroups = g.([]config.LogGroupConfig)
+			}
+
+			for _, logGroup := range logGroups {
+				if _, ok := logsMetrics[logGroup.Name]; !ok {
+					continue
+				}
+				if strings.Contains(logGroup.Name, "/aws/lambda/") {
+					lambdaLogs = append(lambdaLogs, logGroup)
+				} else {
+					applicationLogs = append(applicationLogs, logGroup)
 				}
 			}
 
-			if len(applicationLogs) > 0 {
-				b.WriteString(r.bold("APPLICATION") + r.nl)
-				for lg, data := range applicationLogs {
-					cnt := data.(map[string]int)
-					b.WriteString(fmt.Sprintf("%s:%s", r.esc(lg), r.nl))
-					b.WriteString(fmt.Sprintf("INFO: %d%s", cnt["info"], r.nl))
-					b.WriteString(fmt.Sprintf("WARN: %d%s", cnt["warn"], r.nl))
-					b.WriteString(fmt.Sprintf("ERROR: %d%s", cnt["error"], r.nl))
-					b.WriteString(r.nl)
+			var logErrors map[string][]LogErrorGroup
+			if timeParams.IsDailyReport {
+				if e, ok := allMetrics["cloudwatchLogsErrors"]; ok {
+					logErrors = e.(map[string][]LogErrorGroup)
 				}
 			}
 
-			if len(lambdaLogs) > 0 {
-				b.WriteString(r.bold("LAMBDA") + r.nl)
-				for lg, data := range lambdaLogs {
-					cnt := data.(map[string]int)
-					b.WriteString(fmt.Sprintf("%s:%s", r.esc(lg), r.nl))
-					b.WriteString(fmt.Sprintf("INFO: %d%s", cnt["info"], r.nl))
-					b.WriteString(fmt.Sprintf("WARN: %d%s", cnt["warn"], r.nl))
-					b.WriteString(fmt.Sprintf("ERROR: %d%s", cnt["error"], r.nl))
+			sections := []struct {
+				Title     string
+				LogGroups []config.LogGroupConfig
+			}{
+				{"APPLICATION", applicationLogs},
+				{"LAMBDA", lambdaLogs},
+			}
+
+			for _, section := range sections {
+				if len(section.LogGroups) == 0 {
+					continue
+				}
+				b.WriteString(r.bold(section.Title) + r.nl)
+				for _, logGroup := range section.LogGroups {
+					cnt := logsMetrics[logGroup.Name].(map[string]int)
+					b.WriteString(fmt.Sprintf("%s:%s", r.esc(logGroup.Name), r.nl))
+					for _, counter := range logGroup.Counters {
+						b.WriteString(fmt.Sprintf("%s: %d%s", r.esc(counter.Label), cnt[counter.Label], r.nl))
+					}
+					if topErrors := logErrors[logGroup.Name]; len(topErrors) > 0 {
+						b.WriteString("Top Errors:" + r.nl)
+						for _, topError := range topErrors {
+							firstSeen := topError.FirstSeen.In(timeParams.Location).Format("02/01 15:04")
+							lastSeen := topError.LastSeen.In(timeParams.Location).Format("02/01 15:04")
+							b.WriteString(fmt.Sprintf("%dx %s (%s - %s)%s",
+								topError.Count, r.esc(truncate(topError.Fingerprint, 100)), firstSeen, lastSeen, r.nl))
+						}
+					}
 					b.WriteString(r.nl)
 				}
 			}
@@ -260,3 +941,92 @@ func BuildMessage(cfg *config.Config, timeParams *config.TimeParams, allMetrics
 	}
 	return b.String()
 }
+
+// BuildLogAlertMessage summarizes the log lines forwarded from a subscription filter
+func BuildLogAlertMessage(alert *LogAlert, forEmail bool) string {
+	r := newRenderer(forEmail)
+
+	var b strings.Builder
+	b.WriteString(r.bold("LOG ALERT") + ": " + r.esc(alert.LogGroup) + r.nl)
+
+	// Summary of the lines held back at the end of a rate limit window
+	if alert.Matched == 0 {
+		b.WriteString(fmt.Sprintf("%d matching lines suppressed since the last alert%s", alert.Suppressed, r.nl))
+		if forEmail {
+			return "<html><body style=\"font-family: monospace; white-space: pre-wrap;\">" + b.String() + "</body></html>"
+		}
+		return b.String()
+	}
+
+	b.WriteString(fmt.Sprintf("%d matching lines (%s - %s UTC)%s",
+		alert.Matched, alert.FirstSeen.Format("15:04:05"), alert.LastSeen.Format("15:04:05"), r.nl))
+	if alert.Suppressed > 0 {
+		b.WriteString(fmt.Sprintf("+%d lines suppressed since the last alert%s", alert.Suppressed, r.nl))
+	}
+
+	// Backticks in a line would close the code block
+	var lines []string
+	for _, line := range alert.Lines {
+		lines = append(lines, truncate(strings.ReplaceAll(strings.TrimSpace(line), "`", "'"), 300))
+	}
+	if hidden := alert.Matched - len(alert.Lines); hidden > 0 {
+		lines = append(lines, fmt.Sprintf("... %d more", hidden))
+	}
+	b.WriteString(r.pre(strings.Join(lines, "\n") + "\n"))
+
+	if forEmail {
+		return "<html><body style=\"font-family: monospace; white-space: pre-wrap;\">" + b.String() + "</body></html>"
+	}
+	return b.String()
+}
+
+// severityRank orders finding severities from CRITICAL (0) to INFORMATIONAL
+// (4). Empty or unknown severities rank last.
+func severityRank(severity string) int {
+	ranks := map[string]int{"CRITICAL": 0, "HIGH": 1, "MEDIUM": 2, "LOW": 3, "INFORMATIONAL": 4}
+	if rank, ok := ranks[severity]; ok {
+		return rank
+	}
+	return 5
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// table renders rows as left-aligned monospace columns
+func table(headers []string, rows [][]string) string {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len([]rune(header))
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := len([]rune(cell)); i < len(widths) && w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var t strings.Builder
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				t.WriteString(cell)
+				break
+			}
+			t.WriteString(cell + strings.Repeat(" ", widths[i]-len([]rune(cell))+2))
+		}
+		t.WriteString("\n")
+	}
+
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return t.String()
+}
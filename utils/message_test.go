@@ -0,0 +1,622 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"telegraws/config"
+	"testing"
+	"time"
+)
+
+// messageEndTime is the end of the window every BuildMessage case reports on
+var messageEndTime = time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+
+// messageCase is a BuildMessage case: the metrics to render and the strings
+// the message must and must not contain
+type messageCase struct {
+	name       string
+	daily      bool
+	metrics    map[string]any
+	want       []string
+	wantAbsent []string
+}
+
+// runMessageCases renders every case with a config changed by enable. Reports
+// use a fixed UTC+2 zone so the expected times don't depend on tzdata.
+func runMessageCases(t *testing.T, enable func(cfg *config.Config), cases []messageCase) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	enable(cfg)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			timeParams := &config.TimeParams{
+				EndTime:       messageEndTime,
+				IsDailyReport: tt.daily,
+				Location:      time.FixedZone("UTC+2", 2*60*60),
+			}
+			got := BuildMessage(cfg, timeParams, tt.metrics, false)
+			checkMessage(t, got, tt.want, tt.wantAbsent)
+		})
+	}
+}
+
+// checkMessage expects message to contain every string of want and none of wantAbsent
+func checkMessage(t *testing.T, message string, want, wantAbsent []string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(message, w) {
+			t.Errorf("message does not contain %q\n%s", w, message)
+		}
+	}
+	for _, absent := range wantAbsent {
+		if strings.Contains(message, absent) {
+			t.Errorf("message contains %q\n%s", absent, message)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a longer message", 10, "a longe..."},
+		{"ééééééééééé", 8, "ééééé..."},
+		{"", 5, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.text, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		rows    [][]string
+		want    string
+	}{
+		{
+			name:    "headers only",
+			headers: []string{"PATH", "USED"},
+			want:    "PATH  USED\n",
+		},
+		{
+			name:    "columns fit the widest cell",
+			headers: []string{"PATH", "USED"},
+			rows:    [][]string{{"/", "12%"}, {"/var/lib", "80%"}},
+			want: "PATH      USED\n" +
+				"/         12%\n" +
+				"/var/lib  80%\n",
+		},
+		{
+			name:    "multibyte cells",
+			headers: []string{"NAME", "N"},
+			rows:    [][]string{{"café", "1"}},
+			want:    "NAME  N\ncafé  1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := table(tt.headers, tt.rows); got != tt.want {
+				t.Errorf("table() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMessageRDSEvents(t *testing.T) {
+	rdsEvents := func(n int) map[string]any {
+		report := &RDSReport{ClusterID: "aurora"}
+		for i := 1; i <= n; i++ {
+			report.Events = append(report.Events, RDSEvent{
+				Time:     messageEndTime.Add(time.Duration(i-n) * time.Hour),
+				SourceID: "writer",
+				Message:  fmt.Sprintf("event %d", i),
+			})
+		}
+		return map[string]any{"rds": report}
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.RDS.Enabled = true }, []messageCase{
+		{
+			name:       "all events",
+			daily:      true,
+			metrics:    rdsEvents(3),
+			want:       []string{"Events: 3", "event 1\n", "event 3\n"},
+			wantAbsent: []string{"not shown"},
+		},
+		{
+			name:       "most recent events",
+			daily:      true,
+			metrics:    rdsEvents(maxListItems + 4),
+			want:       []string{"Events: 14", "4 earlier events not shown", "event 5\n", "event 14\n"},
+			wantAbsent: []string{"event 4\n"},
+		},
+	})
+}
+
+func TestBuildMessageDynamoDB(t *testing.T) {
+	dynamoDBTable := func(table *DynamoDBTable) map[string]any {
+		table.TableName = "orders"
+		return map[string]any{"dynamodb": map[string]any{"orders": table}}
+	}
+
+	enable := func(cfg *config.Config) {
+		cfg.Services.DynamoDB.Enabled = true
+		cfg.Services.DynamoDB.TableNames = []string{"orders"}
+	}
+
+	runMessageCases(t, enable, []messageCase{
+		{
+			name: "on-demand",
+			metrics: dynamoDBTable(&DynamoDBTable{
+				OnDemand: true,
+				Metrics:  map[string]float64{"ConsumedReadCapacityUnits": 120},
+			}),
+			want:       []string{"Read Capacity: 120 units (on-demand)", "PITR: disabled"},
+			wantAbsent: []string{"Utilization"},
+		},
+		{
+			name: "provisioned with indexes",
+			metrics: dynamoDBTable(&DynamoDBTable{
+				PITREnabled: true,
+				Metrics: map[string]float64{
+					"ReadCapacityProvisioned":   20,
+					"ReadUtilization_Average":   12.5,
+					"ReadUtilization_Peak":      80,
+					"ConsumedReadCapacityUnits": 9000,
+				},
+				Indexes: []DynamoDBIndex{{
+					IndexName: "by-customer",
+					Metrics:   map[string]float64{"ReadUtilization_Peak": 40, "WriteUtilization_Peak": 5},
+				}},
+			}),
+			want: []string{
+				"Read Capacity: 9000 units\n",
+				"Read Utilization: 12.50% (avg), 80.00% (peak) of 20 RCU",
+				"GSI by-customer Utilization: 40.00% read, 5.00% write (peak)",
+				"PITR: enabled",
+			},
+			wantAbsent: []string{"on-demand", "Write Utilization"},
+		},
+	})
+}
+
+func TestBuildMessageS3Growth(t *testing.T) {
+	s3Bucket := func(metrics map[string]float64) map[string]any {
+		return map[string]any{"s3": []*S3Bucket{{BucketName: "assets", Metrics: metrics}}}
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.S3.Enabled = true }, []messageCase{
+		{
+			name:  "growth since the prior datapoint",
+			daily: true,
+			metrics: s3Bucket(map[string]float64{
+				"BucketSizeBytes": 150, "BucketSizeBytes_Previous": 100,
+				"NumberOfObjects": 40, "NumberOfObjects_Previous": 45,
+			}),
+			want: []string{"Size: 150.00 MB (+50.00 MB day-over-day)", "Objects: 40 (-5 day-over-day)"},
+		},
+		{
+			name:    "first datapoint",
+			daily:   true,
+			metrics: s3Bucket(map[string]float64{"BucketSizeBytes": 150, "NumberOfObjects": 40}),
+			want:    []string{"Size: 150.00 MB (n/a day-over-day)", "Objects: 40 (n/a day-over-day)"},
+		},
+		{
+			name:       "hourly report",
+			metrics:    s3Bucket(map[string]float64{"BucketSizeBytes": 150}),
+			wantAbsent: []string{"assets"},
+		},
+	})
+}
+
+func TestBuildMessageCloudFront(t *testing.T) {
+	distributions := map[string]any{
+		"cloudfront": map[string]any{
+			"E1": map[string]float64{"Requests": 1200, "CacheHitRate": 91.5, "404ErrorRate": 2.25},
+			"E2": map[string]float64{"Requests": 30},
+		},
+	}
+
+	enable := func(additionalMetrics bool) func(cfg *config.Config) {
+		return func(cfg *config.Config) {
+			cfg.Services.CloudFront.Enabled = true
+			cfg.Services.CloudFront.DistributionIDs = []string{"E1", "E2"}
+			cfg.Services.CloudFront.AdditionalMetrics = additionalMetrics
+		}
+	}
+
+	runMessageCases(t, enable(false), []messageCase{
+		{
+			name:       "standard metrics",
+			metrics:    distributions,
+			want:       []string{"CloudFront* E1\nRequests: 1200", "CloudFront* E2\nRequests: 30"},
+			wantAbsent: []string{"Cache Hit Rate"},
+		},
+	})
+	runMessageCases(t, enable(true), []messageCase{
+		{
+			name:    "additional metrics",
+			metrics: distributions,
+			want:    []string{"Cache Hit Rate: 91.50%", "401: 0.00%, 403: 0.00%, 404: 2.25%"},
+		},
+	})
+}
+
+func TestBuildMessageTopErrors(t *testing.T) {
+	enable := func(cfg *config.Config) {
+		cfg.Services.CloudWatchLogs.Enabled = true
+		cfg.Services.CloudWatchLogs.LogGroupNames = []string{"/aws/lambda/orders"}
+	}
+
+	metrics := map[string]any{
+		"cloudwatchLogs": map[string]any{
+			"/aws/lambda/orders": map[string]int{"INFO": 10, "WARN": 2, "ERROR": 3},
+		},
+		"cloudwatchLogsErrors": map[string][]LogErrorGroup{
+			"/aws/lambda/orders": {{
+				Fingerprint: "order <n> not found",
+				Count:       3,
+				FirstSeen:   time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC),
+				LastSeen:    time.Date(2024, 5, 2, 7, 5, 0, 0, time.UTC),
+			}},
+		},
+	}
+
+	// FirstSeen and LastSeen in the report time zone (UTC+2)
+	const topError = "3x order <n> not found (02/05 00:30 - 02/05 09:05)"
+
+	runMessageCases(t, enable, []messageCase{
+		{name: "daily report in the report time zone", daily: true, metrics: metrics, want: []string{topError}},
+		{name: "hourly report", metrics: metrics, wantAbsent: []string{topError}},
+	})
+}
+
+func TestBuildLogAlertMessage(t *testing.T) {
+	firstSeen := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		alert *LogAlert
+		want  string
+	}{
+		{
+			name: "lines",
+			alert: &LogAlert{
+				LogGroup:  "orders",
+				Matched:   3,
+				Lines:     []string{"ERROR timeout ", "ERROR refused"},
+				FirstSeen: firstSeen,
+				LastSeen:  firstSeen.Add(90 * time.Second),
+			},
+			want: "*LOG ALERT*: orders\n" +
+				"3 matching lines (10:00:00 - 10:01:30 UTC)\n" +
+				"```\nERROR timeout\nERROR refused\n... 1 more\n```\n",
+		},
+		{
+			name: "lines after a suppressed burst",
+			alert: &LogAlert{
+				LogGroup:   "orders",
+				Matched:    1,
+				Suppressed: 7,
+				Lines:      []string{"ERROR timeout"},
+				FirstSeen:  firstSeen,
+				LastSeen:   firstSeen,
+			},
+			want: "*LOG ALERT*: orders\n" +
+				"1 matching lines (10:00:00 - 10:00:00 UTC)\n" +
+				"+7 lines suppressed since the last alert\n" +
+				"```\nERROR timeout\n```\n",
+		},
+		{
+			name: "backticks can't close the code block",
+			alert: &LogAlert{
+				LogGroup:  "orders",
+				Matched:   1,
+				Lines:     []string{"ERROR unexpected ``` in `query`"},
+				FirstSeen: firstSeen,
+				LastSeen:  firstSeen,
+			},
+			want: "*LOG ALERT*: orders\n" +
+				"1 matching lines (10:00:00 - 10:00:00 UTC)\n" +
+				"```\nERROR unexpected ''' in 'query'\n```\n",
+		},
+		{
+			name:  "summary of suppressed lines",
+			alert: &LogAlert{LogGroup: "orders", Suppressed: 12},
+			want:  "*LOG ALERT*: orders\n12 matching lines suppressed since the last alert\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildLogAlertMessage(tt.alert, false); got != tt.want {
+				t.Errorf("BuildLogAlertMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMessageCost(t *testing.T) {
+	cost := func(report CostReport) map[string]any {
+		report.Currency = "USD"
+		report.GroupBy = "Service"
+		return map[string]any{"cost": &report}
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.Cost.Enabled = true }, []messageCase{
+		{
+			name:  "change against the previous month",
+			daily: true,
+			metrics: cost(CostReport{
+				Yesterday:           4.2,
+				MonthToDate:         55,
+				PreviousMonthToDate: 50,
+				Forecast:            160,
+				HasForecast:         true,
+				Top:                 []CostItem{{Name: "Amazon RDS", Amount: 30}},
+			}),
+			want: []string{
+				"Yesterday: 4.20 USD",
+				"Month to date: 55.00 USD (+10.0% vs last month)",
+				"Forecast: 160.00 USD",
+				"SERVICE     MTD\nAmazon RDS  30.00\n",
+			},
+		},
+		{
+			name:       "first day of the month",
+			daily:      true,
+			metrics:    cost(CostReport{Yesterday: 4.2}),
+			want:       []string{"Month to date: 0.00 USD\n"},
+			wantAbsent: []string{"vs last month", "Forecast"},
+		},
+		{
+			name:       "hourly report",
+			metrics:    cost(CostReport{Yesterday: 4.2}),
+			wantAbsent: []string{"COST"},
+		},
+	})
+}
+
+func TestBuildMessageBudgetAlert(t *testing.T) {
+	budgets := func(actual, forecast float64) map[string]any {
+		return map[string]any{"budgets": &BudgetsReport{Budgets: []BudgetStatus{{
+			Name:            "monthly",
+			Unit:            "USD",
+			Limit:           100,
+			ActualPercent:   actual,
+			ForecastPercent: forecast,
+		}}}}
+	}
+
+	enable := func(cfg *config.Config) {
+		cfg.Services.Budgets.Enabled = true
+		cfg.Services.Budgets.WarningPercent = 80
+	}
+
+	runMessageCases(t, enable, []messageCase{
+		{
+			name:    "actual over the warning percent",
+			metrics: budgets(85, 130),
+			want:    []string{"*BUDGET ALERT*: monthly at 85%\n"},
+		},
+		{
+			name:       "forecast over the warning percent",
+			metrics:    budgets(50, 90),
+			want:       []string{"*BUDGET ALERT*: monthly forecast at 90%\n"},
+			wantAbsent: []string{"monthly at"},
+		},
+		{
+			name:       "under the warning percent",
+			metrics:    budgets(50, 80),
+			want:       []string{"monthly  50%     80%       100 USD"},
+			wantAbsent: []string{"BUDGET ALERT"},
+		},
+	})
+}
+
+func TestBuildMessageAlarms(t *testing.T) {
+	longReason := "Threshold Crossed: 1 out of the last 1 datapoints " + strings.Repeat("x", 200)
+
+	alarms := func(n int) map[string]any {
+		report := &AlarmsReport{Total: 20}
+		for i := 1; i <= n; i++ {
+			report.Alarms = append(report.Alarms, AlarmState{
+				Name:   fmt.Sprintf("alarm%d", i),
+				State:  "ALARM",
+				Reason: longReason,
+				Since:  messageEndTime.Add(-time.Hour),
+			})
+		}
+		return map[string]any{"alarms": report}
+	}
+
+	// Reasons are truncated to 100 characters
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.Alarms.Enabled = true }, []messageCase{
+		{
+			name:       "all alarms",
+			metrics:    alarms(2),
+			want:       []string{"Not OK: 2 of 20", "ALARM alarm1 (since 02/05 07:00)", "ALARM alarm2 (since", truncate(longReason, 100) + "\n"},
+			wantAbsent: []string{"more", longReason},
+		},
+		{
+			name:       "capped alarms",
+			metrics:    alarms(maxListItems + 3),
+			want:       []string{"Not OK: 13 of 20", "ALARM alarm10 (since", "and 3 more"},
+			wantAbsent: []string{"alarm11", longReason},
+		},
+	})
+}
+
+func TestBuildMessageCertificateExpiry(t *testing.T) {
+	certificate := func(daysLeft int) map[string]any {
+		return map[string]any{"certificates": []Certificate{{
+			Source:     "ACM",
+			Region:     "eu-west-1",
+			DomainName: "example.com",
+			Type:       "AMAZON_ISSUED",
+			NotAfter:   messageEndTime.AddDate(0, 0, daysLeft),
+			DaysLeft:   daysLeft,
+			Severity:   "CRITICAL",
+		}}}
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.Certificates.Enabled = true }, []messageCase{
+		{name: "days left", daily: true, metrics: certificate(12), want: []string{") expires in 12 days, "}},
+		{name: "expires today", daily: true, metrics: certificate(0), want: []string{") expires today, "}},
+		{name: "expired", daily: true, metrics: certificate(-3), want: []string{") expired, "}},
+	})
+}
+
+func TestSeverityRank(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     int
+	}{
+		{"CRITICAL", 0},
+		{"HIGH", 1},
+		{"MEDIUM", 2},
+		{"LOW", 3},
+		{"INFORMATIONAL", 4},
+		{"", 5},
+		{"UNKNOWN", 5},
+		{"critical", 5},
+	}
+
+	for _, tt := range tests {
+		if got := severityRank(tt.severity); got != tt.want {
+			t.Errorf("severityRank(%q) = %d, want %d", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestBuildMessageRoute53(t *testing.T) {
+	healthChecks := map[string]any{"route53": []*Route53HealthCheck{
+		{ID: "hc-1", Name: "api", Endpoint: "api.example.com/health", HasStatus: true,
+			Metrics: map[string]float64{"HealthCheckStatus_Minimum": 1, "HealthCheckPercentageHealthy_Average": 100}},
+		{ID: "hc-2", Endpoint: "192.0.2.10", HasStatus: true, MeasureLatency: true,
+			Metrics: map[string]float64{"HealthCheckStatus_Minimum": 0, "ConnectionTime_Average": 42, "TimeToFirstByte_Average": 120}},
+		{ID: "hc-3", Metrics: map[string]float64{}},
+	}}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.Route53.Enabled = true }, []messageCase{
+		{
+			name:    "status and names",
+			metrics: healthChecks,
+			want: []string{
+				"api: HEALTHY\nHealthy Checkers: 100.0%\n",
+				"192.0.2.10: UNHEALTHY\n",
+				"Connection: 42 ms, TTFB: 120 ms\n",
+				"hc-3: NO DATA\n",
+			},
+		},
+	})
+}
+
+func TestBuildMessageUnattachedVolumes(t *testing.T) {
+	// Sorted by cost like the collector does, vol-1 costs the most
+	volumes := func(n int) map[string]any {
+		report := &EBSReport{}
+		for i := 1; i <= n; i++ {
+			report.Unattached = append(report.Unattached, &EBSVolume{
+				VolumeID:    fmt.Sprintf("vol-%d", i),
+				VolumeType:  "gp3",
+				SizeGB:      10,
+				MonthlyCost: float64(n - i + 1),
+			})
+		}
+		return map[string]any{"ebs": report}
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.EBS.Enabled = true }, []messageCase{
+		{
+			name:       "all volumes",
+			daily:      true,
+			metrics:    volumes(2),
+			want:       []string{"Unattached Volumes: 2 (~$3.00/month)", "vol-1 ", "vol-2 "},
+			wantAbsent: []string{"more"},
+		},
+		{
+			// The total still covers the volumes left out of the table
+			name:       "capped volumes",
+			daily:      true,
+			metrics:    volumes(maxListItems + 2),
+			want:       []string{"Unattached Volumes: 12 (~$78.00/month)", "vol-10 ", "and 2 more"},
+			wantAbsent: []string{"vol-11 "},
+		},
+	})
+}
+
+func TestBuildMessageEFS(t *testing.T) {
+	fileSystems := map[string]any{"efs": []*EFSFileSystem{
+		{
+			FileSystemID: "fs-1",
+			Name:         "shared",
+			Metrics: map[string]float64{
+				"PercentIOLimit": 12, "BurstCreditBalance": 2100, "ClientConnections": 3,
+				"ThroughputUtilization": 87.6, "StorageBytes": 1.5,
+			},
+		},
+		{FileSystemID: "fs-2", Metrics: map[string]float64{"ThroughputUtilization": 4}},
+	}}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.EFS.Enabled = true }, []messageCase{
+		{
+			name:    "peak throughput utilization",
+			metrics: fileSystems,
+			want: []string{
+				"FILE SYSTEM  IO%  BURST GB  CONN  PEAK THRPT  SIZE GB\n",
+				"shared       12%  2100      3     88%         1.50\n",
+				"fs-2         -    -         0     4%          0.00\n",
+			},
+		},
+	})
+}
+
+func TestBuildMessageVPC(t *testing.T) {
+	metrics := map[string]any{
+		"natGateways": []*NATGateway{
+			{
+				NATGatewayID:   "nat-1",
+				Name:           "egress",
+				Metrics:        map[string]float64{"BytesInFromSource": 60, "BytesInFromDestination": 40, "ErrorPortAllocation": 2},
+				ProcessingCost: 4.5,
+			},
+			{
+				NATGatewayID:   "nat-2",
+				Metrics:        map[string]float64{"BytesInFromSource": 10},
+				ProcessingCost: 0.45,
+			},
+		},
+		"vpnTunnels": []VPNTunnel{
+			{VPNConnectionID: "vpn-1", Name: "office", OutsideIP: "198.51.100.1", Status: "UP", LastStatusChange: messageEndTime.Add(-48 * time.Hour)},
+			{VPNConnectionID: "vpn-1", OutsideIP: "198.51.100.2", Status: "DOWN", StatusMessage: "IPSEC IS DOWN", LastStatusChange: messageEndTime.Add(-time.Hour)},
+		},
+	}
+
+	runMessageCases(t, func(cfg *config.Config) { cfg.Services.VPC.Enabled = true }, []messageCase{
+		{
+			name:    "NAT gateways and VPN tunnels",
+			metrics: metrics,
+			want: []string{
+				"egress       0     2         0      0.00\n",
+				"nat-2        0     0         0      0.00\n",
+				"Data Processed: 110.00 GB (~$4.95)\n",
+				"office 198.51.100.1: UP since 30/04 08:00\n",
+				"vpn-1 198.51.100.2: DOWN since 02/05 07:00 (IPSEC IS DOWN)\n",
+			},
+			wantAbsent: []string{"UP since 30/04 08:00 ("},
+		},
+	})
+}
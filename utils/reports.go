@@ -0,0 +1,336 @@
+package utils
+
+import "time"
+
+// Report types shared by the service collectors and the message builder.
+
+// RDSInstance holds the metrics of a single database instance. Role is
+// "writer" or "reader" for Aurora cluster members and empty for standalone
+// instances.
+type RDSInstance struct {
+	InstanceID string
+	Role       string
+	Serverless bool
+	ResourceID string // DbiResourceId, only set when Performance Insights is enabled
+	Metrics    map[string]float64
+	TopSQL     []RDSLoadItem
+	TopWaits   []RDSLoadItem
+}
+
+// RDSLoadItem is a SQL statement or wait event with its average DB load
+// (average active sessions) over the report window.
+type RDSLoadItem struct {
+	Name string
+	Load float64
+}
+
+type RDSEvent struct {
+	Time       time.Time
+	SourceID   string
+	Categories []string
+	Message    string
+}
+
+type RDSReport struct {
+	ClusterID string
+	Cluster   map[string]float64
+	Instances []RDSInstance
+	Events    []RDSEvent
+}
+
+type DynamoDBIndex struct {
+	IndexName string
+	Metrics   map[string]float64
+}
+
+// DynamoDBTable holds the CloudWatch metrics of a table together with the
+// metadata returned by DescribeTable. Utilization metrics are only present
+// for provisioned tables.
+type DynamoDBTable struct {
+	TableName   string
+	OnDemand    bool
+	ItemCount   int64
+	SizeBytes   int64
+	PITREnabled bool
+	LastBackup  time.Time
+	Metrics     map[string]float64
+	Indexes     []DynamoDBIndex
+}
+
+// S3Bucket holds the storage and request metrics of a bucket. Sizes are in
+// MB, StorageClasses is keyed by the CloudWatch StorageType dimension and
+// the "_Previous" metrics hold the prior daily datapoint. They are missing
+// when there is no prior datapoint yet, e.g. for a new bucket.
+type S3Bucket struct {
+	BucketName     string
+	StorageClasses map[string]float64
+	Metrics        map[string]float64
+}
+
+// WAFMetricSet holds the Allowed/Blocked/Counted requests of a web ACL rule
+// or associated resource.
+type WAFMetricSet struct {
+	Name    string
+	Metrics map[string]float64
+}
+
+// CountItem is a named occurrence count, used for top-N lists
+type CountItem struct {
+	Name  string
+	Count int64
+}
+
+type WAFWebACL struct {
+	Name      string
+	Scope     string
+	Metrics   map[string]float64
+	Rules     []WAFMetricSet
+	Resources []WAFMetricSet
+
+	// Sampled blocked requests (daily only)
+	TopBlockedIPs       []CountItem
+	TopBlockedCountries []CountItem
+	TopBlockedURIs      []CountItem
+	TopBlockingRules    []CountItem
+}
+
+type CWAgentDisk struct {
+	Path        string
+	Device      string
+	FSType      string
+	UsedPercent float64
+	InodesUsed  float64
+	InodesTotal float64
+}
+
+// CWAgentProcess holds the procstat metrics of a configured process. Found
+// is false when the agent does not report the process at all.
+type CWAgentProcess struct {
+	Name       string
+	Found      bool
+	PIDCount   float64
+	CPUPercent float64
+	MemoryMB   float64
+}
+
+type CWAgentInstance struct {
+	InstanceID string
+	Metrics    map[string]float64
+	Disks      []CWAgentDisk
+	Processes  []CWAgentProcess
+}
+
+// LogErrorGroup is a set of error messages sharing the same fingerprint
+type LogErrorGroup struct {
+	Fingerprint string
+	Count       int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// LogAlert is a batch of log lines forwarded from a subscription filter, or
+// with Matched 0 a summary of the lines held back by the rate limit
+type LogAlert struct {
+	LogGroup   string
+	Matched    int      // Matching lines in this batch
+	Suppressed int      // Matching lines held back by the rate limit since the last alert
+	Lines      []string // First lines of the batch, capped by maxLines
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+// CostReport holds the daily Cost Explorer figures, all in Currency
+type CostReport struct {
+	Currency            string
+	Yesterday           float64
+	MonthToDate         float64
+	PreviousMonthToDate float64 // Same days of the previous month
+	Forecast            float64 // Month-end total, valid when HasForecast
+	HasForecast         bool
+	GroupBy             string // "Service" or the cost allocation tag key
+	Top                 []CostItem
+}
+
+type CostItem struct {
+	Name   string
+	Amount float64
+}
+
+// BudgetStatus is the spend of a cost or usage budget in the current period
+type BudgetStatus struct {
+	Name            string
+	TimeUnit        string
+	Unit            string
+	Limit           float64
+	Actual          float64
+	Forecast        float64
+	ActualPercent   float64
+	ForecastPercent float64
+}
+
+// CostAnomaly is a Cost Anomaly Detection anomaly with its main root cause.
+// EndDate is empty while the anomaly is ongoing.
+type CostAnomaly struct {
+	StartDate     string
+	EndDate       string
+	Service       string
+	Region        string
+	UsageType     string
+	Impact        float64
+	ImpactPercent float64
+}
+
+type BudgetsReport struct {
+	Budgets   []BudgetStatus
+	Anomalies []CostAnomaly
+}
+
+// HealthEvent is an open or upcoming AWS Health event. Entities holds the
+// first affected resources out of EntityCount.
+type HealthEvent struct {
+	Arn         string
+	Service     string
+	TypeCode    string
+	Category    string
+	Region      string
+	Status      string
+	StartTime   time.Time
+	EndTime     time.Time
+	Entities    []string
+	EntityCount int
+}
+
+// AlarmState is an alarm in ALARM or INSUFFICIENT_DATA since the given time
+type AlarmState struct {
+	Name   string
+	State  string
+	Reason string
+	Since  time.Time
+}
+
+// AlarmsReport holds the alarms needing attention out of Total monitored
+// alarms and their state transitions during the report window
+type AlarmsReport struct {
+	Total          int
+	Alarms         []AlarmState
+	Transitions    int
+	TopTransitions []CountItem
+}
+
+// Certificate is an ACM or IAM server certificate close to its expiry.
+// Severity is CRITICAL within criticalDays and WARNING otherwise.
+type Certificate struct {
+	Source             string
+	Region             string
+	DomainName         string
+	Type               string
+	NotAfter           time.Time
+	DaysLeft           int
+	Severity           string
+	RenewalEligibility string
+	InUseBy            []string
+}
+
+// SecurityFinding is a new GuardDuty finding or a failed Security Hub control.
+// Severity is LOW, MEDIUM, HIGH or CRITICAL (INFORMATIONAL for Security Hub).
+type SecurityFinding struct {
+	Source   string
+	Severity string
+	Title    string
+	Type     string
+	Resource string
+}
+
+// CloudTrailEvent is a notable management event: a root or failed console
+// sign-in, an IAM policy or access key change, a security group ingress
+// change or a trail being stopped. Target is the changed resource.
+type CloudTrailEvent struct {
+	Time      time.Time
+	EventName string
+	User      string
+	SourceIP  string
+	Region    string
+	Target    string
+	Root      bool
+	Failed    bool
+}
+
+// QuotaUsage is the peak usage of a quota over the report window
+type QuotaUsage struct {
+	ServiceCode string
+	QuotaCode   string
+	QuotaName   string
+	Value       float64
+	Usage       float64
+	UsedPercent float64
+}
+
+// Route53HealthCheck holds the metrics of a health check. Endpoint is empty
+// for calculated and CloudWatch alarm health checks.
+type Route53HealthCheck struct {
+	ID             string
+	Name           string
+	Type           string
+	Endpoint       string
+	MeasureLatency bool
+	HasStatus      bool
+	Metrics        map[string]float64
+}
+
+// EBSVolume holds the metrics of a volume. Byte metrics are in MB and
+// MonthlyCost is a storage-only estimate from us-east-1 list prices.
+type EBSVolume struct {
+	VolumeID    string
+	Name        string
+	VolumeType  string
+	SizeGB      int32
+	InstanceID  string
+	Device      string
+	CreateTime  time.Time
+	MonthlyCost float64
+	Metrics     map[string]float64
+}
+
+// EBSReport holds the volumes attached to the monitored instances and, in
+// daily reports, the unattached volumes of the region by monthly cost
+type EBSReport struct {
+	Volumes    []*EBSVolume
+	Unattached []*EBSVolume
+}
+
+// EFSFileSystem holds the metrics of a file system. StorageBytes and
+// BurstCreditBalance are in GB. ThroughputUtilization is the busiest minute
+// of metered throughput as a percentage of the permitted throughput.
+type EFSFileSystem struct {
+	FileSystemID    string
+	Name            string
+	PerformanceMode string
+	ThroughputMode  string
+	Metrics         map[string]float64
+}
+
+// NATGateway holds the metrics of a NAT gateway. Byte metrics are in GB and
+// ProcessingCost is a data processing estimate from us-east-1 list prices.
+type NATGateway struct {
+	NATGatewayID   string
+	Name           string
+	VpcID          string
+	ProcessingCost float64
+	Metrics        map[string]float64
+}
+
+// VPNTunnel holds the state of one tunnel of a VPN connection
+type VPNTunnel struct {
+	VPNConnectionID  string
+	Name             string
+	OutsideIP        string
+	Status           string
+	StatusMessage    string
+	LastStatusChange time.Time
+}
+
+// TransitGateway holds the metrics of a transit gateway. Byte metrics are in GB.
+type TransitGateway struct {
+	TransitGatewayID string
+	Metrics          map[string]float64
+}